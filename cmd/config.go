@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fijdemon/gssh/internal/config"
+)
+
+// RunConfig 执行配置文件相关操作（validate）
+func RunConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: gssh config validate [--json] [path]")
+	}
+
+	switch args[0] {
+	case "validate":
+		return runConfigValidate(args[1:])
+	default:
+		return fmt.Errorf("未知的 config 子命令: %s", args[0])
+	}
+}
+
+// runConfigValidate 检查配置文件（未指定 path 时包括 conf.d / include 中的服务器文件）
+// 逐条输出 file:line 形式的问题，有问题时返回错误
+func runConfigValidate(args []string) error {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "以 JSON 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var issues []config.Issue
+	var err error
+	switch fs.NArg() {
+	case 0:
+		issues, err = config.Validate()
+	case 1:
+		issues, err = config.ValidateFile(fs.Arg(0))
+	default:
+		return fmt.Errorf("用法: gssh config validate [--json] [path]")
+	}
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		if issues == nil {
+			issues = []config.Issue{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("配置文件存在 %d 个问题", len(issues))
+	}
+	if !*jsonOutput {
+		fmt.Println("配置检查通过")
+	}
+	return nil
+}
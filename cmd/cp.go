@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/ssh"
+)
+
+// RunCopy 在本地与服务器之间复制文件
+// 用法: gssh cp [-r] <local> <server>:<path> 或 gssh cp [-r] <server>:<path> <local>
+func RunCopy(args []string) error {
+	fs := flag.NewFlagSet("cp", flag.ContinueOnError)
+	recursive := fs.Bool("r", false, "递归复制目录")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("用法: gssh cp [-r] <local> <server>:<path> 或 gssh cp [-r] <server>:<path> <local>")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	src, dst := fs.Arg(0), fs.Arg(1)
+	srcServer, srcPath := splitRemote(cfg, src)
+	dstServer, dstPath := splitRemote(cfg, dst)
+
+	switch {
+	case srcServer != nil && dstServer != nil:
+		return fmt.Errorf("不支持在两台服务器之间直接复制")
+	case srcServer == nil && dstServer == nil:
+		return fmt.Errorf("源路径和目标路径中必须有一个是 <server>:<path>")
+	}
+
+	server := srcServer
+	if server == nil {
+		server = dstServer
+	}
+
+	client, err := ssh.NewServerClient(cfg, *server)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	progress := newProgressPrinter()
+	if dstServer != nil {
+		err = ssh.Upload(client, src, dstPath, *recursive, progress.update)
+	} else {
+		err = ssh.Download(client, srcPath, dst, *recursive, progress.update)
+	}
+	progress.finish()
+	if err != nil {
+		return fmt.Errorf("复制失败: %w", err)
+	}
+
+	fmt.Printf("复制完成，共 %d 个文件，%s\n", progress.files, formatBytes(progress.totalBytes))
+	return nil
+}
+
+// splitRemote 解析 <server>:<path>，冒号前不是已配置的服务器时按本地路径处理
+func splitRemote(cfg *config.Config, arg string) (*config.Server, string) {
+	idx := strings.Index(arg, ":")
+	if idx <= 0 {
+		return nil, arg
+	}
+
+	server, err := cfg.GetServer(arg[:idx])
+	if err != nil {
+		return nil, arg
+	}
+
+	path := arg[idx+1:]
+	if path == "" {
+		path = "."
+	}
+	return server, path
+}
+
+// progressPrinter 在终端单行刷新传输进度
+type progressPrinter struct {
+	current    string
+	start      time.Time
+	last       time.Time
+	files      int
+	totalBytes int64
+}
+
+func newProgressPrinter() *progressPrinter {
+	return &progressPrinter{}
+}
+
+// update 传输进度回调
+func (p *progressPrinter) update(name string, done, total int64) {
+	now := time.Now()
+	if name != p.current {
+		if p.current != "" {
+			fmt.Fprintln(os.Stderr)
+		}
+		p.current = name
+		p.start = now
+		p.files++
+	}
+
+	finished := done >= total
+	if finished {
+		p.totalBytes += total
+	}
+	// 限制刷新频率，文件结束时总是刷新
+	if !finished && now.Sub(p.last) < 100*time.Millisecond {
+		return
+	}
+	p.last = now
+
+	percent := int64(100)
+	if total > 0 {
+		percent = done * 100 / total
+	}
+	speed := ""
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		speed = formatBytes(int64(float64(done)/elapsed)) + "/s"
+	}
+	fmt.Fprintf(os.Stderr, "\r%-40s %3d%% %10s / %-10s %12s", filepath.Base(name), percent, formatBytes(done), formatBytes(total), speed)
+}
+
+// finish 结束进度输出
+func (p *progressPrinter) finish() {
+	if p.current != "" {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// formatBytes 以易读的单位显示字节数
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
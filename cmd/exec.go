@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/ssh"
+	"github.com/fijdemon/gssh/internal/util"
+	"github.com/fijdemon/gssh/internal/vault"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// execResult 单台服务器的执行结果
+type execResult struct {
+	Name       string `json:"name"`
+	Hostname   string `json:"hostname"`
+	ExitCode   int    `json:"exit_code"`
+	Stdout     string `json:"stdout,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// stringList 可重复指定的命令行参数
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+// RunExec 在匹配分组/标签的所有服务器上并行执行命令
+// 用法: gssh exec [-g group] [-t tag...] [--parallel N] [--timeout 60s] [--json] -- <command>
+func RunExec(args []string) error {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	group := fs.String("g", "", "按分组筛选服务器")
+	var tags stringList
+	fs.Var(&tags, "t", "按标签筛选服务器（可重复指定或逗号分隔）")
+	parallel := fs.Int("parallel", 5, "同时执行的服务器数量")
+	timeout := fs.Duration("timeout", 60*time.Second, "每台服务器的超时时间（包含连接）")
+	jsonOutput := fs.Bool("json", false, "以 JSON 输出每台服务器的结果")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	command := strings.Join(fs.Args(), " ")
+	if command == "" {
+		return fmt.Errorf("用法: gssh exec [-g group] [-t tag...] [--parallel N] [--timeout 60s] [--json] -- <command>")
+	}
+	if *parallel < 1 {
+		*parallel = 1
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	servers := cfg.FilterServers(tags, *group)
+	if len(servers) == 0 {
+		return fmt.Errorf("没有匹配的服务器")
+	}
+	// 并行连接时不在终端交互，需要主密码时先在这里询问一次（--json 时同样询问，提示输出到标准错误）
+	if err := unlockVault(cfg, servers); err != nil {
+		return err
+	}
+	if !*jsonOutput {
+		fmt.Fprintf(os.Stderr, "在 %d 台服务器上执行: %s\n", len(servers), command)
+	}
+
+	// 计算主机名前缀宽度，使输出对齐
+	width := 0
+	for _, s := range servers {
+		width = max(width, util.DisplayWidth(s.Name))
+	}
+
+	var outMu sync.Mutex
+	results := make([]execResult, len(servers))
+	sem := make(chan struct{}, *parallel)
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var stdout, stderr io.Writer
+			var stdoutBuf, stderrBuf bytes.Buffer
+			var flushers []*prefixWriter
+			if *jsonOutput {
+				stdout, stderr = &stdoutBuf, &stderrBuf
+			} else {
+				prefix := fmt.Sprintf("[%s] ", util.PadRight(s.Name, width))
+				outW := &prefixWriter{prefix: prefix, out: os.Stdout, mu: &outMu}
+				errW := &prefixWriter{prefix: prefix, out: os.Stderr, mu: &outMu}
+				flushers = append(flushers, outW, errW)
+				stdout, stderr = outW, errW
+			}
+
+			results[i] = runOnServer(cfg, s, command, *timeout, stdout, stderr)
+			for _, f := range flushers {
+				f.Flush()
+			}
+			if *jsonOutput {
+				results[i].Stdout = stdoutBuf.String()
+				results[i].Stderr = stderrBuf.String()
+			} else if results[i].Error != "" {
+				outMu.Lock()
+				fmt.Fprintf(os.Stderr, "[%s] 错误: %s\n", util.PadRight(s.Name, width), results[i].Error)
+				outMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" || r.ExitCode != 0 {
+			failed++
+		}
+	}
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("序列化结果失败: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printExecSummary(results, width)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d/%d 台服务器执行失败", failed, len(results))
+	}
+	return nil
+}
+
+// unlockVault 服务器（或其跳板机）的密码保存在已锁定的保险库中时，在终端提示输入一次主密码
+// 非交互环境下不询问，需要该密码的服务器各自报告保险库已锁定（提示先运行 'gssh vault unlock'）
+func unlockVault(cfg *config.Config, servers []config.Server) error {
+	if !util.IsTerminal() || vault.Unlocked() {
+		return nil
+	}
+	for _, s := range servers {
+		hops := []config.Server{s}
+		if chain, err := cfg.ResolveJumpChain(s.Name); err == nil {
+			hops = append(hops, chain...)
+		}
+		for _, hop := range hops {
+			if vault.IsSealed(hop.Auth.Password) {
+				_, err := vault.Reveal(hop.Auth.Password)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runOnServer 连接服务器并执行命令，连接与执行共用同一个超时时间
+// 使用不在终端交互的连接：未知主机、需要输入的密码等作为该服务器的错误返回，不会与其他服务器争用终端
+func runOnServer(cfg *config.Config, s config.Server, command string, timeout time.Duration, stdout, stderr io.Writer) (result execResult) {
+	result = execResult{Name: s.Name, Hostname: s.Hostname, ExitCode: -1}
+	start := time.Now()
+	defer func() {
+		result.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type dialResult struct {
+		client *gossh.Client
+		err    error
+	}
+	dialed := make(chan dialResult, 1)
+	go func() {
+		client, err := ssh.NewBatchServerClient(cfg, s)
+		dialed <- dialResult{client, err}
+	}()
+
+	var client *gossh.Client
+	select {
+	case <-ctx.Done():
+		// 连接超时，连接稍后建立成功也要关闭
+		go func() {
+			if r := <-dialed; r.client != nil {
+				r.client.Close()
+			}
+		}()
+		result.Error = fmt.Sprintf("连接超时（%s）", timeout)
+		return result
+	case r := <-dialed:
+		if r.err != nil {
+			result.Error = r.err.Error()
+			return result
+		}
+		client = r.client
+	}
+	defer client.Close()
+
+	exitCode, err := ssh.RunCommand(ctx, client, command, stdout, stderr)
+	if err != nil {
+		if ctx.Err() != nil {
+			result.Error = fmt.Sprintf("执行超时（%s）", timeout)
+		} else {
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	result.ExitCode = exitCode
+	return result
+}
+
+// printExecSummary 打印执行结果汇总表
+func printExecSummary(results []execResult, width int) {
+	width = max(width, util.DisplayWidth("服务器"))
+	fmt.Println()
+	fmt.Printf("%s  %s  %s  %s\n", util.PadRight("服务器", width), util.PadRight("退出码", 6), util.PadRight("耗时", 8), "结果")
+	for _, r := range results {
+		status := "成功"
+		code := fmt.Sprintf("%d", r.ExitCode)
+		switch {
+		case r.Error != "":
+			status = "失败: " + r.Error
+			code = "-"
+		case r.ExitCode != 0:
+			status = "失败"
+		}
+		duration := (time.Duration(r.DurationMs) * time.Millisecond).Round(100 * time.Millisecond)
+		fmt.Printf("%s  %-6s  %-8s  %s\n", util.PadRight(r.Name, width), code, duration, status)
+	}
+}
+
+// prefixWriter 按行给输出加上主机名前缀，多台服务器的输出按整行交错
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		w.writeLine(w.buf[:idx+1])
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush 输出最后一行不以换行结尾的内容
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprint(w.out, w.prefix)
+	w.out.Write(line)
+}
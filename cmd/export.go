@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fijdemon/gssh/internal/config"
+)
+
+// RunExport 导出服务器供其他工具使用
+// 用法: gssh export ssh-config [-g group] [-t tag...] [--write | --disable]
+func RunExport(args []string) error {
+	if len(args) == 0 || args[0] != "ssh-config" {
+		return fmt.Errorf("用法: gssh export ssh-config [-g group] [-t tag...] [--write | --disable]")
+	}
+
+	fs := flag.NewFlagSet("export ssh-config", flag.ContinueOnError)
+	group := fs.String("g", "", "按分组筛选服务器")
+	var tags stringList
+	fs.Var(&tags, "t", "按标签筛选服务器（可重复指定或逗号分隔）")
+	write := fs.Bool("write", false, "写入 ~/.gssh/ssh_config 并在 ~/.ssh/config 中 Include，之后每次保存配置自动更新")
+	disable := fs.Bool("disable", false, "停止自动更新并删除生成的文件")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *write && *disable {
+		return fmt.Errorf("--write 和 --disable 不能同时使用")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	switch {
+	case *write:
+		return writeSSHConfigExport(*group, tags)
+	case *disable:
+		return disableSSHConfigExport(cfg)
+	default:
+		fmt.Print(config.RenderSSHConfig(cfg.ExportServers(*group, tags)))
+		return nil
+	}
+}
+
+// writeSSHConfigExport 启用自动导出，生成文件并确保 ~/.ssh/config 引用它
+func writeSSHConfigExport(group string, tags []string) error {
+	cfg, err := config.Update(func(latest *config.Config) error {
+		latest.Export.SSHConfig = true
+		latest.Export.Group = group
+		latest.Export.Tags = tags
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	// Save 生成失败时只会警告，这里再生成一次以便返回错误
+	if err := config.WriteSSHConfigExport(cfg); err != nil {
+		return err
+	}
+
+	path, err := cfg.GetSSHConfigExportPath()
+	if err != nil {
+		return err
+	}
+
+	added, err := config.EnsureSSHConfigInclude(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ 已导出 %d 台服务器到 %s\n", len(cfg.ExportServers(group, tags)), path)
+	if added {
+		fmt.Println("✓ 已在 ~/.ssh/config 开头添加 Include")
+	}
+	fmt.Println("之后每次修改服务器都会自动更新该文件，运行 'gssh export ssh-config --disable' 可停止")
+	return nil
+}
+
+// disableSSHConfigExport 停止自动导出并删除生成的文件（~/.ssh/config 中的 Include 保留，文件不存在时 ssh 会忽略）
+func disableSSHConfigExport(cfg *config.Config) error {
+	if !cfg.Export.SSHConfig {
+		fmt.Println("未启用 ssh_config 自动导出")
+		return nil
+	}
+
+	path, err := cfg.GetSSHConfigExportPath()
+	if err != nil {
+		return err
+	}
+
+	if _, err := config.Update(func(cfg *config.Config) error {
+		cfg.Export = config.ExportConfig{}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除 %s 失败: %w", path, err)
+	}
+
+	fmt.Printf("✓ 已停止自动导出并删除 %s\n", path)
+	return nil
+}
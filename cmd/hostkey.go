@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/ssh"
+	"github.com/fijdemon/gssh/internal/util"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// RunHostKey 执行主机密钥管理操作（list / forget / trust）
+func RunHostKey(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: gssh hostkey list|forget <server>|trust <server>|trust --sync [profile]")
+	}
+
+	switch args[0] {
+	case "list":
+		return listHostKeys()
+	case "forget":
+		if len(args) < 2 {
+			return fmt.Errorf("用法: gssh hostkey forget <server>")
+		}
+		return forgetHostKey(args[1])
+	case "trust":
+		if len(args) < 2 {
+			return fmt.Errorf("用法: gssh hostkey trust <server>|--sync [profile]")
+		}
+		if args[1] == "--sync" {
+			profile := ""
+			if len(args) > 2 {
+				profile = args[2]
+			}
+			return trustSyncHostKeys(profile)
+		}
+		return trustHostKey(args[1])
+	default:
+		return fmt.Errorf("未知的 hostkey 子命令: %s", args[0])
+	}
+}
+
+// listHostKeys 列出已信任的主机密钥
+func listHostKeys() error {
+	entries, err := ssh.ListHostKeys()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("尚未记录任何主机密钥")
+		return nil
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%-40s %-20s %s\n", strings.Join(e.Hosts, ","), e.Key.Type(), gossh.FingerprintSHA256(e.Key))
+	}
+	return nil
+}
+
+// forgetHostKey 删除服务器已记录的主机密钥
+func forgetHostKey(name string) error {
+	address, err := serverAddress(name)
+	if err != nil {
+		return err
+	}
+
+	removed, err := ssh.ForgetHostKey(address)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		fmt.Printf("没有找到 %s (%s) 的主机密钥记录\n", name, address)
+		return nil
+	}
+
+	fmt.Printf("已删除 %s (%s) 的 %d 条主机密钥记录\n", name, address, removed)
+	return nil
+}
+
+// trustHostKey 获取服务器当前的主机密钥，确认后写入 known_hosts
+// 配置了跳板机的服务器经由跳板机链获取（与登录时的路径一致）
+func trustHostKey(name string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	server, err := cfg.GetServer(name)
+	if err != nil {
+		return err
+	}
+	jumps, err := ssh.ServerJumps(cfg, *server)
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(server.Hostname, strconv.Itoa(server.GetPort()))
+	key, err := ssh.FetchHostKey(address, jumps...)
+	if err != nil {
+		return err
+	}
+	return confirmHostKey(name, address, key)
+}
+
+// trustSyncHostKeys 确认并信任 SSH 同步服务器的主机密钥（后台自动同步不会询问未知主机）
+// profile 为空时处理所有使用 SSH 同步的目标
+func trustSyncHostKeys(profile string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	names := []string{config.DefaultSyncTarget}
+	for _, p := range cfg.SyncProfiles {
+		names = append(names, p.Name)
+	}
+	if profile != "" {
+		names = []string{profile}
+	}
+
+	trusted := make(map[string]bool)
+	for _, name := range names {
+		sc, err := cfg.SyncTarget(name)
+		if err != nil {
+			return err
+		}
+		if sc.Type != "ssh" || sc.SSHHost == "" {
+			if profile != "" {
+				return fmt.Errorf("同步目标 %s 没有使用 SSH 同步", name)
+			}
+			continue
+		}
+		port := sc.SSHPort
+		if port == 0 {
+			port = 22
+		}
+		address := net.JoinHostPort(sc.SSHHost, strconv.Itoa(port))
+		if trusted[address] {
+			continue
+		}
+		trusted[address] = true
+
+		key, err := ssh.FetchHostKey(address)
+		if err != nil {
+			return err
+		}
+		if err := confirmHostKey("同步服务器 "+name, address, key); err != nil {
+			return err
+		}
+	}
+	if len(trusted) == 0 {
+		fmt.Println("没有使用 SSH 同步的同步目标")
+	}
+	return nil
+}
+
+// confirmHostKey 显示主机密钥指纹，确认后替换 known_hosts 中该地址的记录
+func confirmHostKey(name, address string, key gossh.PublicKey) error {
+	fmt.Printf("主机: %s (%s)\n", name, address)
+	fmt.Printf("%s 密钥指纹: %s\n", key.Type(), gossh.FingerprintSHA256(key))
+	fmt.Print("是否信任该主机密钥? (y/N): ")
+	var answer string
+	fmt.Scanln(&answer)
+	if !util.IsYes(answer) {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	// 先删除旧记录，避免新旧密钥同时存在
+	if _, err := ssh.ForgetHostKey(address); err != nil {
+		return err
+	}
+	if err := ssh.AddHostKey(address, key); err != nil {
+		return err
+	}
+
+	fmt.Printf("已信任 %s 的主机密钥\n", name)
+	return nil
+}
+
+// serverAddress 根据服务器名称获取 host:port 地址
+func serverAddress(name string) (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	server, err := cfg.GetServer(name)
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(server.Hostname, strconv.Itoa(server.GetPort())), nil
+}
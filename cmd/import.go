@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/sshconfig"
+	"github.com/fijdemon/gssh/internal/util"
+)
+
+// RunImport 从其他工具导入服务器
+// 用法: gssh import ssh-config [--dry-run] [-g group] [path]
+func RunImport(args []string) error {
+	if len(args) == 0 || args[0] != "ssh-config" {
+		return fmt.Errorf("用法: gssh import ssh-config [--dry-run] [-g group] [path]")
+	}
+
+	fs := flag.NewFlagSet("import ssh-config", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "只预览将要导入的服务器，不修改配置")
+	group := fs.String("g", "", "导入的服务器所属分组")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	path := fs.Arg(0)
+	if path == "" {
+		p, err := sshconfig.DefaultPath()
+		if err != nil {
+			return err
+		}
+		path = p
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	hosts, warnings, err := sshconfig.Parse(path)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "警告: %s\n", w)
+	}
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		fmt.Printf("%s 中没有可导入的主机（含通配符的 Host 会被跳过）\n", path)
+		return nil
+	}
+
+	candidates := sshconfig.Plan(cfg, hosts, *group)
+	printImportPlan(candidates)
+
+	conflicts := 0
+	for _, c := range candidates {
+		if c.Conflict {
+			conflicts++
+		}
+	}
+	if conflicts == len(candidates) {
+		fmt.Println("\n所有主机都已存在，无需导入")
+		return nil
+	}
+	if *dryRun {
+		fmt.Printf("\n预览模式：将导入 %d 台服务器，%d 台因名称冲突跳过\n", len(candidates)-conflicts, conflicts)
+		return nil
+	}
+
+	// 在最新的配置上导入，避免覆盖其他 gssh 进程在此期间的修改
+	var added []string
+	if _, err := config.Update(func(latest *config.Config) error {
+		added, err = sshconfig.Apply(latest, candidates, nil)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✓ 已导入 %d 台服务器", len(added))
+	if conflicts > 0 {
+		fmt.Printf("，%d 台因名称冲突跳过", conflicts)
+	}
+	fmt.Println()
+	return nil
+}
+
+// printImportPlan 打印待导入服务器列表
+func printImportPlan(candidates []sshconfig.Candidate) {
+	rows := [][]string{{"名称", "地址", "用户", "跳板机", "状态"}}
+	for _, c := range candidates {
+		status := "新增"
+		if c.Conflict {
+			status = "跳过（名称已存在）"
+		} else if c.Source == "" {
+			status = "新增（ProxyJump 跳板机）"
+		}
+		rows = append(rows, []string{
+			c.Server.Name,
+			fmt.Sprintf("%s:%d", c.Server.Hostname, c.Server.GetPort()),
+			c.Server.User,
+			strings.Join(c.Server.Jump, ","),
+			status,
+		})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, col := range row {
+			widths[i] = max(widths[i], util.DisplayWidth(col))
+		}
+	}
+	for _, row := range rows {
+		var b strings.Builder
+		for i, col := range row {
+			if i == len(row)-1 {
+				b.WriteString(col)
+			} else {
+				b.WriteString(util.PadRight(col, widths[i]+2))
+			}
+		}
+		fmt.Println(b.String())
+	}
+}
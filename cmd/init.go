@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/fijdemon/gssh/internal/config"
 	"github.com/fijdemon/gssh/internal/util"
@@ -39,46 +40,22 @@ func RunInit() error {
 			return
 		}
 
-		fmt.Print("同步服务器地址 (例如: sync.example.com): ")
-		var host string
-		fmt.Scanln(&host)
-		if host == "" {
-			fmt.Print("同步服务器地址为空,取消同步设置")
-			return
-		}
-		cfg.Sync.Enabled = true
-		cfg.Sync.Type = "ssh"
-		cfg.Sync.SSHHost = host
-
-		fmt.Print("SSH 用户名: ")
-		var user string
-		fmt.Scanln(&user)
-		cfg.Sync.SSHUser = user
-
-		fmt.Print("远程配置文件路径 (默认: ~/.gssh/config.yaml): ")
-		var path string
-		fmt.Scanln(&path)
-		if path == "" {
-			path = "~/.gssh/config.yaml"
+		fmt.Print("同步方式? (ssh/http/git/webdav) [ssh]: ")
+		var syncType string
+		fmt.Scanln(&syncType)
+		var ok bool
+		switch syncType {
+		case "http", "https", "h":
+			ok = initHTTPSync(cfg)
+		case "git", "g":
+			ok = initGitSync(cfg)
+		case "webdav", "w":
+			ok = initWebDAVSync(cfg)
+		default:
+			ok = initSSHSync(cfg)
 		}
-		cfg.Sync.SSHPath = path
-
-		fmt.Print("使用密钥认证还是密码认证? (key/password) [key]: ")
-		var authType string
-		fmt.Scanln(&authType)
-		if authType == "password" || authType == "p" {
-			fmt.Print("SSH 密码: ")
-			var password string
-			fmt.Scanln(&password)
-			cfg.Sync.Password = password
-		} else {
-			fmt.Print("SSH 密钥路径 (例如: ~/.ssh/id_rsa) [~/.ssh/id_rsa]: ")
-			var keyPath string
-			fmt.Scanln(&keyPath)
-			if keyPath == "" {
-				keyPath = "~/.ssh/id_rsa"
-			}
-			cfg.Sync.SSHKey = keyPath
+		if !ok {
+			return
 		}
 
 		fmt.Print("启动时自动同步? (y/N): ")
@@ -101,3 +78,162 @@ func RunInit() error {
 
 	return nil
 }
+
+// initSSHSync 交互式设置 SSH 同步，返回是否设置成功
+func initSSHSync(cfg *config.Config) bool {
+	fmt.Print("同步服务器地址 (例如: sync.example.com): ")
+	var host string
+	fmt.Scanln(&host)
+	if host == "" {
+		fmt.Print("同步服务器地址为空,取消同步设置")
+		return false
+	}
+	cfg.Sync.Enabled = true
+	cfg.Sync.Type = "ssh"
+	cfg.Sync.SSHHost = host
+
+	fmt.Print("SSH 端口 [22]: ")
+	var port string
+	fmt.Scanln(&port)
+	if port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			fmt.Printf("无效的端口: %s,取消同步设置", port)
+			return false
+		}
+		if p != 22 {
+			cfg.Sync.SSHPort = p
+		}
+	}
+
+	fmt.Print("SSH 用户名: ")
+	var user string
+	fmt.Scanln(&user)
+	cfg.Sync.SSHUser = user
+
+	fmt.Print("远程配置文件路径 (默认: ~/.gssh/config.yaml): ")
+	var path string
+	fmt.Scanln(&path)
+	if path == "" {
+		path = "~/.gssh/config.yaml"
+	}
+	cfg.Sync.SSHPath = path
+
+	fmt.Print("使用密钥认证还是密码认证? (key/password) [key]: ")
+	var authType string
+	fmt.Scanln(&authType)
+	if authType == "password" || authType == "p" {
+		fmt.Print("SSH 密码: ")
+		var password string
+		fmt.Scanln(&password)
+		cfg.Sync.Password = password
+	} else {
+		fmt.Print("SSH 密钥路径 (例如: ~/.ssh/id_rsa) [~/.ssh/id_rsa]: ")
+		var keyPath string
+		fmt.Scanln(&keyPath)
+		if keyPath == "" {
+			keyPath = "~/.ssh/id_rsa"
+		}
+		cfg.Sync.SSHKey = keyPath
+	}
+	return true
+}
+
+// initHTTPSync 交互式设置 HTTP(S) 同步，返回是否设置成功
+func initHTTPSync(cfg *config.Config) bool {
+	fmt.Print("配置文件地址 (例如: https://sync.example.com/gssh/config.yaml): ")
+	var url string
+	fmt.Scanln(&url)
+	if url == "" {
+		fmt.Print("配置文件地址为空,取消同步设置")
+		return false
+	}
+	cfg.Sync.Enabled = true
+	cfg.Sync.Type = "http"
+	cfg.Sync.URL = url
+
+	fmt.Print("认证方式? (token/basic/none) [token]: ")
+	var authType string
+	fmt.Scanln(&authType)
+	switch authType {
+	case "none", "n":
+	case "basic", "b":
+		fmt.Print("用户名: ")
+		var username string
+		fmt.Scanln(&username)
+		cfg.Sync.Username = username
+		password, err := util.ReadPassword("密码: ")
+		if err != nil {
+			fmt.Printf("读取密码失败: %v\n", err)
+			return false
+		}
+		cfg.Sync.Password = password
+	default:
+		token, err := util.ReadPassword("Token: ")
+		if err != nil {
+			fmt.Printf("读取 Token 失败: %v\n", err)
+			return false
+		}
+		cfg.Sync.Token = token
+	}
+	return true
+}
+
+// initWebDAVSync 交互式设置 WebDAV 同步，返回是否设置成功
+func initWebDAVSync(cfg *config.Config) bool {
+	fmt.Print("WebDAV 地址 (例如: https://cloud.example.com/remote.php/dav/files/alice): ")
+	var url string
+	fmt.Scanln(&url)
+	if url == "" {
+		fmt.Print("WebDAV 地址为空,取消同步设置")
+		return false
+	}
+	cfg.Sync.Enabled = true
+	cfg.Sync.Type = "webdav"
+	cfg.Sync.URL = url
+
+	fmt.Print("配置文件路径 [gssh/config.yaml]: ")
+	var path string
+	fmt.Scanln(&path)
+	cfg.Sync.WebDAVPath = path
+
+	fmt.Print("用户名 (留空表示不需要认证): ")
+	var username string
+	fmt.Scanln(&username)
+	if username == "" {
+		return true
+	}
+	cfg.Sync.Username = username
+	password, err := util.ReadPassword("密码 (Nextcloud 建议使用应用密码): ")
+	if err != nil {
+		fmt.Printf("读取密码失败: %v\n", err)
+		return false
+	}
+	cfg.Sync.Password = password
+	return true
+}
+
+// initGitSync 交互式设置 Git 同步，返回是否设置成功
+func initGitSync(cfg *config.Config) bool {
+	fmt.Print("Git 仓库地址 (例如: git@github.com:team/gssh-servers.git): ")
+	var repo string
+	fmt.Scanln(&repo)
+	if repo == "" {
+		fmt.Print("Git 仓库地址为空,取消同步设置")
+		return false
+	}
+	cfg.Sync.Enabled = true
+	cfg.Sync.Type = "git"
+	cfg.Sync.GitRepo = repo
+
+	fmt.Print("分支 [main]: ")
+	var branch string
+	fmt.Scanln(&branch)
+	cfg.Sync.GitBranch = branch
+
+	fmt.Print("配置文件在仓库中的路径 [config.yaml]: ")
+	var path string
+	fmt.Scanln(&path)
+	cfg.Sync.GitPath = path
+	return true
+}
@@ -1,11 +1,34 @@
 package cmd
 
 import (
+	"flag"
+
 	"github.com/fijdemon/gssh/internal/sync"
 )
 
 // RunPull 执行拉取操作
-func RunPull() error {
-	return sync.Pull()
-}
+// 用法: gssh pull [--profile name] [--prefer local|remote] [-y] [--dry-run|--diff] [--json]
+func RunPull(args []string) error {
+	fs := flag.NewFlagSet("pull", flag.ContinueOnError)
+	profile := fs.String("profile", "", "只同步指定的 sync profile（default 表示 sync 配置），不指定时同步所有已启用的目标")
+	prefer := fs.String("prefer", "", "合并冲突时优先使用的一方（local 或 remote），不指定时逐个询问")
+	yes := fs.Bool("y", false, "拉取会删除本地服务器时不再确认")
+	dryRun := fs.Bool("dry-run", false, "只显示拉取会对本地配置做的修改，不写入")
+	diff := fs.Bool("diff", false, "同 --dry-run")
+	jsonOutput := fs.Bool("json", false, "以 JSON 输出 --dry-run 的结果")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
 
+	opts := sync.Options{Prefer: *prefer, Yes: *yes, Profile: *profile}
+	if *dryRun || *diff {
+		plans, err := sync.PreviewPull(opts)
+		if len(plans) > 0 {
+			if perr := printPlans(plans, *prefer, *jsonOutput, err != nil); perr != nil {
+				return perr
+			}
+		}
+		return err
+	}
+	return sync.Pull(opts)
+}
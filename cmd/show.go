@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/config"
+)
+
+// RunShow 显示服务器展开 defaults 和模板之后的实际配置，继承的字段标出来自哪一层
+// 用法: gssh show <server>
+func RunShow(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("用法: gssh show <server>")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	server, err := cfg.GetServer(args[0])
+	if err != nil {
+		return err
+	}
+
+	layers := cfg.ServerLayers(*server)
+	origin := func(field string) string {
+		return layerLabel(layers[field], server.Template)
+	}
+	printField := func(field, value, note string) {
+		if note != "" {
+			value += "  (" + note + ")"
+		}
+		fmt.Printf("%-20s %s\n", field+":", value)
+	}
+	printOptional := func(field, value string) {
+		if value != "" {
+			printField(field, value, origin(field))
+		}
+	}
+
+	printField("name", server.Name, "")
+	printField("hostname", server.Hostname, "")
+	printField("user", server.User, origin("user"))
+	if server.Port > 0 {
+		printField("port", fmt.Sprint(server.Port), origin("port"))
+	} else {
+		printField("port", "22", "默认")
+	}
+	printOptional("description", server.Description)
+	printOptional("group", server.Group)
+	if len(server.Tags) > 0 {
+		printField("tags", strings.Join(server.Tags, ", "), origin("tags"))
+	}
+	printOptional("template", server.Template)
+
+	printField("auth.type", server.Auth.Type, origin("auth.type"))
+	if server.Auth.Password != "" {
+		printField("auth.password", "******", "")
+	}
+	printOptional("auth.password_cmd", server.Auth.PasswordCmd)
+	printOptional("auth.identity_file", server.Auth.IdentityFile)
+	printOptional("auth.passphrase_cmd", server.Auth.PassphraseCmd)
+
+	if len(server.Jump) > 0 {
+		printField("jump", strings.Join(server.Jump, " -> "), origin("jump"))
+	}
+	for _, f := range server.Forwards {
+		target := f.Target
+		if target == "" {
+			target = "-"
+		}
+		printField("forward", fmt.Sprintf("%s %s %s -> %s", f.Name, f.Type, f.Listen, target), "")
+	}
+
+	source := server.Source
+	if source == "" {
+		if source, err = config.GetConfigPath(); err != nil {
+			return err
+		}
+	}
+	printField("file", source, "")
+	printOptional("last_used", server.LastUsed)
+	printOptional("created_at", server.CreatedAt)
+	return nil
+}
+
+// layerLabel 返回继承来源的说明，服务器自己设置的字段返回空
+func layerLabel(layer, template string) string {
+	switch layer {
+	case config.LayerDefaults:
+		return "defaults"
+	case config.LayerTemplate:
+		return "模板 " + template
+	}
+	return ""
+}
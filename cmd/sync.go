@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/sync"
+	"github.com/fijdemon/gssh/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// RunSync 管理同步后端保存的历史版本（log / show / rollback）
+// 配置了多个同步目标时用 --profile 指定，默认为 sync 配置
+func RunSync(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: gssh sync log|show|rollback [--profile name] [-y] [<id>]")
+	}
+
+	fs := flag.NewFlagSet("sync "+args[0], flag.ContinueOnError)
+	profile := fs.String("profile", "", "sync profile 名称，默认为 sync 配置")
+	yes := fs.Bool("y", false, "不询问，直接回滚")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "log":
+		return syncLog(*profile)
+	case "show":
+		if fs.NArg() != 1 {
+			return fmt.Errorf("用法: gssh sync show [--profile name] <id>")
+		}
+		return syncShow(*profile, fs.Arg(0))
+	case "rollback":
+		if fs.NArg() != 1 {
+			return fmt.Errorf("用法: gssh sync rollback [--profile name] [-y] <id>")
+		}
+		return syncRollback(*profile, fs.Arg(0), *yes)
+	default:
+		return fmt.Errorf("未知的 sync 子命令: %s", args[0])
+	}
+}
+
+// syncLog 列出历史版本
+func syncLog(profile string) error {
+	history, err := sync.History(profile)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		fmt.Println("远程还没有历史版本（下一次 'gssh push' 之后开始记录）")
+		return nil
+	}
+
+	fmt.Printf("%s  %s  %s  %s  %s\n",
+		util.PadRight("ID", 17), util.PadRight("时间", 19), util.PadRight("推送者", 24), util.PadRight("服务器", 6), "备注")
+	for i, h := range history {
+		note := h.Note
+		if i == 0 {
+			note = strings.TrimSpace("(当前) " + note)
+		}
+		fmt.Printf("%s  %s  %s  %s  %s\n",
+			util.PadRight(h.ID, 17), util.PadRight(formatHistoryTime(h.Time), 19),
+			util.PadRight(h.User+"@"+h.Host, 24), util.PadRight(fmt.Sprint(h.Count), 6), note)
+	}
+	return nil
+}
+
+// syncShow 打印历史版本的服务器列表（密码不显示）
+func syncShow(profile, id string) error {
+	entry, servers, err := sync.ShowVersion(profile, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("# 版本 %s，%s 由 %s@%s 推送，%d 台服务器\n", entry.ID, formatHistoryTime(entry.Time), entry.User, entry.Host, len(servers))
+	if entry.Note != "" {
+		fmt.Printf("# %s\n", entry.Note)
+	}
+	data, err := yaml.Marshal(struct {
+		Servers []config.Server `yaml:"servers"`
+	}{maskPasswords(servers)})
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// syncRollback 将远程和本地的服务器列表恢复到历史版本
+func syncRollback(profile, id string, yes bool) error {
+	var restored int
+	confirmed := false
+	err := sync.Rollback(profile, id, func(entry *config.HistoryEntry, servers []config.Server) (bool, error) {
+		restored = len(servers)
+		if !yes {
+			if !util.IsTerminal() {
+				return false, fmt.Errorf("非交互环境下请使用 -y 确认回滚")
+			}
+			fmt.Printf("将远程和本地的服务器列表恢复到 %s（%s@%s 推送，%d 台服务器）\n", entry.ID, entry.User, entry.Host, len(servers))
+			fmt.Print("本地未推送的修改会丢失（当前配置会备份到 config.yaml.backup），继续? (y/N): ")
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if !util.IsYes(strings.TrimSpace(answer)) {
+				fmt.Println("已取消回滚")
+				return false, nil
+			}
+		}
+		confirmed = true
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if confirmed {
+		fmt.Printf("已回滚到 %s，共 %d 个服务器配置\n", id, restored)
+	}
+	return nil
+}
+
+// formatHistoryTime 将 RFC3339 时间转换为本地时间显示
+func formatHistoryTime(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// maskPasswords 返回隐藏了密码的服务器列表副本
+func maskPasswords(servers []config.Server) []config.Server {
+	masked := make([]config.Server, len(servers))
+	copy(masked, servers)
+	for i := range masked {
+		if masked[i].Auth.Password != "" {
+			masked[i].Auth.Password = "******"
+		}
+	}
+	return masked
+}
+
+// printPlans 打印 pull / push 预览的差异，prefer 为空时冲突按保留本地的值计算
+// JSON 输出时只有一个同步目标输出单个对象，多个目标输出数组；partial 表示还有同步目标预览失败
+func printPlans(plans []*sync.Plan, prefer string, jsonOutput, partial bool) error {
+	if jsonOutput {
+		var value any = plans
+		if len(plans) == 1 {
+			value = plans[0]
+		}
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fmt.Errorf("序列化结果失败: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	labeled := len(plans) > 1 || partial || plans[0].Profile != config.DefaultSyncTarget
+	for i, plan := range plans {
+		if i > 0 {
+			fmt.Println()
+		}
+		if labeled {
+			fmt.Printf("[%s] ", plan.Profile)
+		}
+		printPlan(plan, prefer)
+	}
+	return nil
+}
+
+// printPlan 以易读的格式打印一个同步目标的差异
+func printPlan(plan *sync.Plan, prefer string) {
+	target := "本地配置"
+	if plan.Direction == "push" {
+		target = "远程配置"
+	}
+	if len(plan.Changes) == 0 {
+		fmt.Printf("%s不会有任何修改\n", target)
+	} else {
+		fmt.Printf("%s将做以下修改（预览，未写入）：\n", target)
+	}
+
+	var added, removed, changed int
+	for _, c := range plan.Changes {
+		switch c.Kind {
+		case sync.ChangeAdded:
+			added++
+			fmt.Printf("  + %s  %s\n", c.Name, c.Target)
+		case sync.ChangeRemoved:
+			removed++
+			fmt.Printf("  - %s  %s\n", c.Name, c.Target)
+		case sync.ChangeChanged:
+			changed++
+			fmt.Printf("  ~ %s\n", c.Name)
+			for _, f := range c.Fields {
+				fmt.Printf("      %s: %s -> %s\n", f.Field, f.Old, f.New)
+			}
+		}
+	}
+
+	if len(plan.Conflicts) > 0 {
+		switch prefer {
+		case sync.PreferLocal:
+			fmt.Println("冲突（--prefer local，保留本地的值）：")
+		case sync.PreferRemote:
+			fmt.Println("冲突（--prefer remote，使用远程的值）：")
+		default:
+			fmt.Println("冲突（实际执行时会逐个询问，预览中保留本地的值）：")
+		}
+		for _, c := range plan.Conflicts {
+			fmt.Printf("  %s\n", c)
+		}
+	}
+	if len(plan.Changes) > 0 {
+		fmt.Printf("共 %d 个新增，%d 个删除，%d 个修改\n", added, removed, changed)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/ssh"
+	"github.com/fijdemon/gssh/internal/tunnel"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// RunTunnel 按服务器配置的端口转发建立隧道，断线后自动重连
+// 用法: gssh tunnel <server> [forward...]，不指定转发名称时启用全部转发
+func RunTunnel(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: gssh tunnel <server> [forward...]")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	server, err := cfg.GetServer(args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(server.Forwards) == 0 {
+		return fmt.Errorf("服务器 '%s' 没有配置端口转发（forwards）", server.Name)
+	}
+
+	forwards := server.Forwards
+	if len(args) > 1 {
+		forwards = nil
+		for _, name := range args[1:] {
+			found := false
+			for _, f := range server.Forwards {
+				if f.Name == name {
+					forwards = append(forwards, f)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("服务器 '%s' 没有名为 '%s' 的端口转发", server.Name, name)
+			}
+		}
+	}
+
+	t, err := tunnel.New(server.Name, forwards, func() (*gossh.Client, error) {
+		return ssh.NewServerClient(cfg, *server)
+	}, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("正在为 %s (%s) 建立端口转发，按 Ctrl+C 退出\n", server.Name, server.GetAddress())
+	return t.Run(ctx)
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/util"
+	"github.com/fijdemon/gssh/internal/vault"
+)
+
+// RunVault 执行保险库管理操作（init / unlock / lock / rekey / pubkey）
+func RunVault(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: gssh vault init|unlock [-t 15m]|lock|rekey|pubkey")
+	}
+
+	switch args[0] {
+	case "init":
+		return vaultInit()
+	case "unlock":
+		return vaultUnlock(args[1:])
+	case "lock":
+		if err := vault.Lock(); err != nil {
+			return err
+		}
+		fmt.Println("保险库已锁定")
+		return nil
+	case "rekey":
+		return vaultRekey()
+	case "pubkey":
+		// 公钥用于同步加密的 recipients，可以公开分享
+		v, err := requireVault()
+		if err != nil {
+			return err
+		}
+		fmt.Println(v.PublicKey)
+		return nil
+	default:
+		return fmt.Errorf("未知的 vault 子命令: %s", args[0])
+	}
+}
+
+// vaultInit 初始化保险库并加密现有的明文密码
+func vaultInit() error {
+	existing, err := vault.Load()
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("保险库已初始化，如需更换主密码请运行 'gssh vault rekey'")
+	}
+
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		return err
+	}
+
+	v, err := vault.Create(passphrase)
+	if err != nil {
+		return err
+	}
+	if err := v.Save(); err != nil {
+		return err
+	}
+
+	// 保存时会用保险库公钥加密所有明文密码
+	count := 0
+	if _, err := config.Update(func(cfg *config.Config) error {
+		for _, secret := range cfg.Secrets() {
+			if *secret != "" && !vault.IsSealed(*secret) {
+				count++
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	// 保存时生成的备份仍是明文，再保存一次让备份也变成加密后的内容
+	if _, err := config.Update(func(*config.Config) error { return nil }); err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+
+	fmt.Printf("✅ 保险库初始化成功，已加密 %d 个密码\n", count)
+	fmt.Println("请牢记主密码，遗忘后已加密的密码将无法恢复")
+	return nil
+}
+
+// vaultUnlock 解锁保险库并在指定时间内缓存
+func vaultUnlock(args []string) error {
+	fs := flag.NewFlagSet("vault unlock", flag.ContinueOnError)
+	ttl := fs.Duration("t", 15*time.Minute, "解锁有效期")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// 无法缓存时在询问主密码之前就失败，避免输入后才报错
+	if err := vault.CanCacheSession(); err != nil {
+		return err
+	}
+
+	v, err := requireVault()
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := util.ReadPassword("请输入保险库主密码: ")
+	if err != nil {
+		return err
+	}
+	priv, err := v.Unlock(passphrase)
+	if err != nil {
+		return err
+	}
+
+	if err := vault.SaveSession(priv, *ttl); err != nil {
+		return err
+	}
+	fmt.Printf("保险库已解锁，有效期至 %s\n", time.Now().Add(*ttl).Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// vaultRekey 更换主密码并使用新的密钥对重新加密所有密码
+func vaultRekey() error {
+	v, err := requireVault()
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := util.ReadPassword("请输入当前主密码: ")
+	if err != nil {
+		return err
+	}
+	priv, err := v.Unlock(passphrase)
+	if err != nil {
+		return err
+	}
+
+	newPassphrase, err := readNewPassphrase()
+	if err != nil {
+		return err
+	}
+	newVault, err := vault.Create(newPassphrase)
+	if err != nil {
+		return err
+	}
+
+	cfg, count, err := config.Rekey(v, priv, newVault)
+	if err != nil {
+		return err
+	}
+
+	// 旧的解锁缓存已失效
+	vault.Lock()
+
+	fmt.Printf("✅ 主密码已更换，重新加密了 %d 个密码\n", count)
+	recipients := cfg.Sync.Encryption.Mode == vault.PayloadRecipients
+	for _, p := range cfg.SyncProfiles {
+		recipients = recipients || p.Encryption.Mode == vault.PayloadRecipients
+	}
+	if recipients {
+		fmt.Println("保险库公钥已更换，请将 'gssh vault pubkey' 的新公钥发给团队成员，更新他们的 sync.encryption.recipients")
+	}
+	return nil
+}
+
+// requireVault 加载保险库，未初始化时返回错误
+func requireVault() (*vault.Vault, error) {
+	v, err := vault.Load()
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("保险库未初始化，请先运行 'gssh vault init'")
+	}
+	return v, nil
+}
+
+// readNewPassphrase 读取并确认新的主密码
+func readNewPassphrase() (string, error) {
+	passphrase, err := util.ReadPassword("请输入新的主密码: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("主密码不能为空")
+	}
+	confirm, err := util.ReadPassword("请再次输入主密码: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != confirm {
+		return "", fmt.Errorf("两次输入的主密码不一致")
+	}
+	return passphrase, nil
+}
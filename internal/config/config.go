@@ -5,47 +5,137 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/fijdemon/gssh/internal/vault"
 )
 
 // Config 主配置结构
 type Config struct {
-	Version string     `yaml:"version"`
-	Sync    SyncConfig `yaml:"sync"`
-	Servers []Server   `yaml:"servers"`
+	Version string       `yaml:"version"`
+	Sync    SyncConfig   `yaml:"sync"`
+	Export  ExportConfig `yaml:"export,omitempty"`
+
+	// Defaults 所有服务器共用的默认值，Templates 服务器通过 template 引用的模板
+	// 服务器的实际配置按 defaults → 模板 → 服务器 的顺序逐层覆盖
+	Defaults  ServerTemplate            `yaml:"defaults,omitempty"`
+	Templates map[string]ServerTemplate `yaml:"templates,omitempty"`
+
+	Servers []Server `yaml:"servers"`
+
+	// SyncProfiles 命名的同步目标，各自只同步筛选条件匹配的服务器，其余服务器由 sync 同步
+	SyncProfiles []SyncProfile `yaml:"sync_profiles,omitempty"`
+
+	// Include 额外读取的服务器文件（支持 ~ 和通配符，相对路径相对于 ~/.gssh），~/.gssh/conf.d/*.yaml 总是会读取
+	Include []string `yaml:"include,omitempty"`
+
+	// Encrypted 端到端加密的服务器列表，只出现在推送到同步后端的配置中（此时 Servers 为空）
+	Encrypted *vault.Payload `yaml:"encrypted,omitempty"`
+	// History 同步后端保留的历史版本（最新的在前），只出现在推送到同步后端的配置中
+	History []HistoryEntry `yaml:"history,omitempty"`
+
+	// includes 已读取的服务器文件，保存时把服务器写回各自的来源文件
+	includes []*includeFile
+}
+
+// HistoryEntry 同步后端保存的一个历史版本
+type HistoryEntry struct {
+	ID        string         `yaml:"id"`             // 推送时间，例如 20260102-150405
+	Time      string         `yaml:"time"`           // 推送时间（RFC3339）
+	User      string         `yaml:"user"`           // 推送者的本地用户名
+	Host      string         `yaml:"host"`           // 推送者的主机名
+	Count     int            `yaml:"count"`          // 服务器数量
+	Note      string         `yaml:"note,omitempty"` // 备注，例如回滚来源
+	Servers   []Server       `yaml:"servers,omitempty"`
+	Encrypted *vault.Payload `yaml:"encrypted,omitempty"` // 启用同步加密时的服务器列表密文
+}
+
+// ExportConfig 导出设置（由 gssh export ssh-config --write 维护）
+type ExportConfig struct {
+	SSHConfig bool     `yaml:"ssh_config"`      // 每次保存配置后重新生成 ssh_config 文件
+	Path      string   `yaml:"path,omitempty"`  // 生成的文件路径，默认 ~/.gssh/ssh_config
+	Group     string   `yaml:"group,omitempty"` // 只导出该分组
+	Tags      []string `yaml:"tags,omitempty"`  // 只导出包含任一标签的服务器
 }
 
 // SyncConfig 同步配置
 type SyncConfig struct {
-	Enabled  bool   `yaml:"enabled"`
-	Type     string `yaml:"type"`      // ssh, http, ftp (未来扩展)
-	SSHHost  string `yaml:"ssh_host"`  // SSH同步时的主机地址
-	SSHUser  string `yaml:"ssh_user"`  // SSH同步时的用户名
-	SSHPath  string `yaml:"ssh_path"`  // SSH同步时的远程路径
-	SSHKey   string `yaml:"ssh_key"`   // SSH密钥路径（可选）
-	Password string `yaml:"password"`  // 密码（可选，用于SSH认证）
-	AutoSync bool   `yaml:"auto_sync"` // 启动时自动同步
-	LastSync string `yaml:"last_sync"` // 最后同步时间
+	Enabled       bool   `yaml:"enabled"`
+	Type          string `yaml:"type"`                     // ssh, http, git, webdav
+	SSHHost       string `yaml:"ssh_host"`                 // SSH同步时的主机地址
+	SSHPort       int    `yaml:"ssh_port,omitempty"`       // SSH同步时的端口（默认 22）
+	SSHUser       string `yaml:"ssh_user"`                 // SSH同步时的用户名
+	SSHPath       string `yaml:"ssh_path"`                 // SSH同步时的远程路径
+	SSHKey        string `yaml:"ssh_key"`                  // SSH密钥路径（可选）
+	GitRepo       string `yaml:"git_repo,omitempty"`       // Git同步时的仓库地址
+	GitBranch     string `yaml:"git_branch,omitempty"`     // Git同步时的分支，默认 main
+	GitPath       string `yaml:"git_path,omitempty"`       // Git同步时配置文件在仓库中的路径，默认 config.yaml
+	URL           string `yaml:"url,omitempty"`            // HTTP同步时的配置文件地址，WebDAV同步时的服务器地址
+	WebDAVPath    string `yaml:"webdav_path,omitempty"`    // WebDAV同步时配置文件相对于 url 的路径，默认 gssh/config.yaml
+	Token         string `yaml:"token,omitempty"`          // HTTP/WebDAV同步时的 Bearer Token（可选）
+	Username      string `yaml:"username,omitempty"`       // HTTP/WebDAV同步时 Basic 认证的用户名（可选，密码使用 password）
+	Password      string `yaml:"password"`                 // 密码（可选，用于SSH认证或HTTP Basic认证）
+	PasswordCmd   string `yaml:"password_cmd,omitempty"`   // 获取密码的外部命令（可选，未配置 password 时使用）
+	PassphraseCmd string `yaml:"passphrase_cmd,omitempty"` // 获取密钥密码的外部命令（可选）
+	AutoSync      bool   `yaml:"auto_sync"`                // 启动时自动同步
+	LastSync      string `yaml:"last_sync"`                // 最后同步时间
+	ETag          string `yaml:"etag,omitempty"`           // HTTP/WebDAV同步时最后一次拉取/推送的远程版本，推送时用于检测冲突
+	HistoryLimit  int    `yaml:"history_limit,omitempty"`  // 同步后端保留的历史版本数量（默认 10）
+
+	Encryption SyncEncryption `yaml:"encryption,omitempty"` // 同步内容端到端加密（可选）
+}
+
+// SyncProfile 命名的同步目标：独立的同步后端（字段与 sync 相同），只同步 groups / tags 匹配的服务器
+// 服务器属于第一个匹配的 profile，都不匹配的服务器属于 sync；groups 和 tags 都为空时匹配所有服务器
+type SyncProfile struct {
+	Name       string   `yaml:"name"`
+	Groups     []string `yaml:"groups,omitempty"` // 同步这些分组的服务器
+	Tags       []string `yaml:"tags,omitempty"`   // 同步包含任一标签的服务器
+	SyncConfig `yaml:",inline"`
+}
+
+// SyncEncryption 同步内容端到端加密设置，启用后同步后端只保存密文
+type SyncEncryption struct {
+	Mode          string   `yaml:"mode,omitempty"`           // passphrase（共享口令）或 recipients（成员公钥），为空表示不加密
+	Passphrase    string   `yaml:"passphrase,omitempty"`     // 共享口令（启用保险库时加密存储）
+	PassphraseCmd string   `yaml:"passphrase_cmd,omitempty"` // 获取共享口令的外部命令
+	Recipients    []string `yaml:"recipients,omitempty"`     // 团队成员的保险库公钥（gssh vault pubkey），本机公钥自动包含
 }
 
 // Server 服务器配置
 type Server struct {
 	Name        string     `yaml:"name"`
 	Hostname    string     `yaml:"hostname"`
-	User        string     `yaml:"user"`
-	Port        int        `yaml:"port"`
+	User        string     `yaml:"user,omitempty"`
+	Port        int        `yaml:"port,omitempty"` // 省略时为 22
 	Description string     `yaml:"description"`
-	Tags        []string   `yaml:"tags"`
-	Group       string     `yaml:"group"` // 分组
-	Auth        AuthConfig `yaml:"auth"`
+	Tags        []string   `yaml:"tags,omitempty"`
+	Group       string     `yaml:"group,omitempty"`    // 分组
+	Template    string     `yaml:"template,omitempty"` // 引用的模板名称
+	Auth        AuthConfig `yaml:"auth,omitempty"`
+	Jump        []string   `yaml:"jump,omitempty"`     // 跳板机链（按顺序引用其他服务器名称）
+	Forwards    []Forward  `yaml:"forwards,omitempty"` // 端口转发配置
 	LastUsed    string     `yaml:"last_used"`
 	CreatedAt   string     `yaml:"created_at"`
+
+	// Source 服务器所在的文件（conf.d 或 include 中的文件），为空表示主配置文件；不写入配置
+	Source string `yaml:"-"`
 }
 
 // AuthConfig 认证配置
 type AuthConfig struct {
-	Type         string `yaml:"type"`          // auto, password, key
-	Password     string `yaml:"password"`      // 密码（不加密存储）
-	IdentityFile string `yaml:"identity_file"` // 密钥文件路径
+	Type          string `yaml:"type,omitempty"`           // auto, password, key
+	Password      string `yaml:"password,omitempty"`       // 密码（启用保险库时加密存储）
+	PasswordCmd   string `yaml:"password_cmd,omitempty"`   // 获取密码的外部命令，例如 pass show prod/web
+	IdentityFile  string `yaml:"identity_file,omitempty"`  // 密钥文件路径
+	PassphraseCmd string `yaml:"passphrase_cmd,omitempty"` // 获取密钥密码的外部命令
+}
+
+// Forward 端口转发配置
+type Forward struct {
+	Name   string `yaml:"name"`   // 转发名称
+	Type   string `yaml:"type"`   // local, remote, dynamic
+	Listen string `yaml:"listen"` // 监听地址，例如 127.0.0.1:5432，只写端口时监听 127.0.0.1
+	Target string `yaml:"target"` // 目标地址，例如 10.0.0.5:5432（dynamic 不需要）
 }
 
 // GetConfigPath 获取配置文件路径
@@ -82,6 +172,14 @@ func (s *Server) GetDisplayName() string {
 	return fmt.Sprintf("[%s] %s", s.Name, s.Description)
 }
 
+// GetPort 获取端口，未配置时返回默认的 22
+func (s *Server) GetPort() int {
+	if s.Port > 0 {
+		return s.Port
+	}
+	return 22
+}
+
 // GetAddress 获取完整地址
 func (s *Server) GetAddress() string {
 	if s.Port > 0 && s.Port != 22 {
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// includeFile 从 conf.d 或 include 读取的服务器文件
+type includeFile struct {
+	path    string
+	version string   // 读取时文件的格式版本，写回时升级到 CurrentVersion
+	servers []Server // 读取时（展开 defaults / 模板之前）的服务器列表，保存时用于判断文件是否需要写回
+	ignored []Server // 与先读取的服务器重名而没有使用的服务器，写回时原样保留
+}
+
+// includeDocument 服务器文件的内容：只包含格式版本和服务器列表
+type includeDocument struct {
+	Version string   `yaml:"version"`
+	Servers []Server `yaml:"servers"`
+}
+
+// GetIncludeDir 获取服务器文件目录 ~/.gssh/conf.d
+func GetIncludeDir() (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "conf.d"), nil
+}
+
+// IncludePaths 返回需要读取的服务器文件：先是 conf.d/*.yaml（按文件名排序），再是 include 中的路径
+// include 支持 ~ 和通配符，相对路径相对于 ~/.gssh；重复的文件只读取一次
+// missing 为 include 中没有匹配到任何文件的条目
+func (c *Config) IncludePaths() (paths, missing []string, err error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return nil, nil, err
+	}
+	configDir := filepath.Dir(configPath)
+	seen := map[string]bool{configPath: true}
+	add := func(matches []string) {
+		for _, p := range matches {
+			if abs, err := filepath.Abs(p); err == nil {
+				p = abs
+			}
+			if !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(configDir, "conf.d", "*.yaml"))
+	if err != nil {
+		return nil, nil, err
+	}
+	add(matches)
+
+	for _, pattern := range c.Include {
+		p, err := expandPath(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, nil, err
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(configDir, p)
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("include 路径 %s 无效: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			missing = append(missing, pattern)
+		}
+		add(matches)
+	}
+	return paths, missing, nil
+}
+
+// loadIncludes 读取服务器文件，将其中的服务器追加到 Servers 并记录来源文件
+// 与已有服务器（主配置或先读取的文件）重名的服务器不使用，由 Validate 报告；include 中不存在的文件忽略
+func (c *Config) loadIncludes() error {
+	paths, _, err := c.IncludePaths()
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(c.Servers))
+	for _, s := range c.Servers {
+		names[s.Name] = true
+	}
+	for _, path := range paths {
+		doc, version, err := readServerFile(path)
+		if err != nil {
+			return err
+		}
+		f := &includeFile{path: path, version: version, servers: doc.Servers}
+		for i := range doc.Servers {
+			doc.Servers[i].Source = path
+			if names[doc.Servers[i].Name] {
+				f.ignored = append(f.ignored, doc.Servers[i])
+				continue
+			}
+			names[doc.Servers[i].Name] = true
+			c.Servers = append(c.Servers, doc.Servers[i])
+		}
+		c.includes = append(c.includes, f)
+	}
+	return nil
+}
+
+// readServerFile 读取并解析服务器文件，旧版本的文件在内存中升级
+func readServerFile(path string) (*includeDocument, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取服务器文件失败: %w", err)
+	}
+	node, version, err := migrateConfig(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w（%s）", err, path)
+	}
+	var doc includeDocument
+	if err := node.Decode(&doc); err != nil {
+		return nil, "", fmt.Errorf("解析服务器文件 %s 失败: %w", path, err)
+	}
+	return &doc, version, nil
+}
+
+// mainServers 返回属于主配置文件的服务器（来源不是任何已读取的服务器文件）
+func (c *Config) mainServers() []Server {
+	servers := []Server{}
+	for _, s := range c.Servers {
+		if c.includeFile(s.Source) == nil {
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+// includeFile 返回路径对应的服务器文件，不是服务器文件时返回 nil
+func (c *Config) includeFile(path string) *includeFile {
+	if path == "" {
+		return nil
+	}
+	for _, f := range c.includes {
+		if f.path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// saveIncludes 将服务器写回各自的来源文件，服务器列表没有变化的文件不写入（保留注释和格式）
+// 调用方需持有配置锁
+func (c *Config) saveIncludes() error {
+	for _, f := range c.includes {
+		servers := []Server{}
+		for _, s := range c.Servers {
+			if s.Source == f.path {
+				servers = append(servers, c.storedServer(s))
+			}
+		}
+		servers = append(servers, f.ignored...)
+
+		data, err := yaml.Marshal(&includeDocument{Version: CurrentVersion, Servers: servers})
+		if err != nil {
+			return fmt.Errorf("序列化服务器文件失败: %w", err)
+		}
+		if loaded, err := yaml.Marshal(&includeDocument{Version: CurrentVersion, Servers: f.servers}); err == nil && bytes.Equal(data, loaded) {
+			continue
+		}
+		perm := os.FileMode(0600)
+		if info, err := os.Stat(f.path); err == nil {
+			perm = info.Mode().Perm()
+		}
+		if f.version != CurrentVersion {
+			if old, err := os.ReadFile(f.path); err == nil {
+				if _, _, err := backupBeforeMigration(f.path, f.version, old); err != nil {
+					return err
+				}
+			}
+		}
+		if err := util.WriteFileAtomic(f.path, data, perm); err != nil {
+			return fmt.Errorf("写入服务器文件 %s 失败: %w", f.path, err)
+		}
+		f.version = CurrentVersion
+		f.servers = servers
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ResolveJumpChain 解析服务器的跳板机链，按连接顺序返回各跳板机（不包含服务器本身）
+// 第一个跳板机自身配置的 jump 也会生效，后续跳板机直接通过上一跳连接
+func (c *Config) ResolveJumpChain(name string) ([]Server, error) {
+	return c.resolveJumpChain(name, []string{})
+}
+
+// resolveJumpChain 递归解析跳板机链，path 记录当前解析路径用于检测循环引用
+func (c *Config) resolveJumpChain(name string, path []string) ([]Server, error) {
+	server, err := c.GetServer(name)
+	if err != nil {
+		return nil, err
+	}
+
+	path = append(path, name)
+	var chain []Server
+	for i, hopName := range server.Jump {
+		hopName = strings.TrimSpace(hopName)
+		if hopName == "" {
+			continue
+		}
+		if slices.Contains(path, hopName) {
+			return nil, fmt.Errorf("跳板机循环引用: %s -> %s", strings.Join(path, " -> "), hopName)
+		}
+		for _, h := range chain {
+			if h.Name == hopName {
+				return nil, fmt.Errorf("服务器 '%s' 的跳板机链中 '%s' 重复出现", name, hopName)
+			}
+		}
+
+		hop, err := c.GetServer(hopName)
+		if err != nil {
+			return nil, fmt.Errorf("服务器 '%s' 引用的跳板机 '%s' 不存在", name, hopName)
+		}
+
+		// 只有第一个跳板机需要继续解析它自己的跳板机
+		if i == 0 {
+			sub, err := c.resolveJumpChain(hopName, path)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, sub...)
+		}
+		chain = append(chain, *hop)
+	}
+
+	return chain, nil
+}
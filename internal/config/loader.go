@@ -5,10 +5,13 @@ import (
 	"os"
 	"time"
 
+	"github.com/fijdemon/gssh/internal/util"
 	"gopkg.in/yaml.v3"
 )
 
-// Load 加载配置文件
+// Load 加载配置文件以及 conf.d / include 中的服务器文件，配置文件不存在时创建默认配置
+// 旧版本的配置文件会自动升级并写回，升级前的文件备份为 config.yaml.v<版本>.backup
+// 配置检查发现的问题只作为警告报告（同一问题在进程内只报告一次），有问题的服务器在连接时才会报错，不影响其他服务器使用
 func Load() (*Config, error) {
 	configPath, err := GetConfigPath()
 	if err != nil {
@@ -17,55 +20,137 @@ func Load() (*Config, error) {
 
 	// 如果配置文件不存在，创建默认配置
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		cfg := NewDefaultConfig()
-		if err := Save(cfg); err != nil {
+		if err := Save(NewDefaultConfig()); err != nil {
 			return nil, fmt.Errorf("创建默认配置失败: %w", err)
 		}
-		return cfg, nil
+	}
+
+	cfg, migrated, err := read()
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		// 升级结果写回配置文件，之后不再重复升级；写回失败不影响本次使用
+		if saved, err := Update(func(*Config) error { return nil }); err != nil {
+			fmt.Fprintf(os.Stderr, "警告: 保存升级后的配置失败: %v\n", err)
+		} else {
+			cfg = saved
+		}
+	}
+
+	reportIssues()
+	return cfg, nil
+}
+
+// load 读取并解析配置文件，不存在时返回默认配置（不写入）
+// 配置文件总是整体替换，读取时不需要加锁
+func load() (*Config, error) {
+	cfg, _, err := read()
+	return cfg, err
+}
+
+// read 读取并解析配置文件和服务器文件，旧版本的配置在内存中升级到 CurrentVersion（不写回），migrated 表示主配置发生了升级
+func read() (cfg *Config, migrated bool, err error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return nil, false, err
 	}
 
 	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		cfg = NewDefaultConfig()
+		if err := cfg.loadIncludes(); err != nil {
+			return nil, false, err
+		}
+		return cfg, false, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+		return nil, false, fmt.Errorf("读取配置文件失败: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	doc, from, err := migrateConfig(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w（%s）", err, configPath)
+	}
+	cfg = &Config{}
+	if err := doc.Decode(cfg); err != nil {
+		return nil, false, fmt.Errorf("解析配置文件失败: %w", err)
 	}
 
-	// 设置默认值
+	if from != CurrentVersion {
+		// 先备份再使用升级后的配置，之后任何写入都不会丢失原始文件
+		backupPath, created, err := backupBeforeMigration(configPath, from, data)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			fmt.Fprintf(os.Stderr, "配置文件已从版本 %s 升级到 %s，原文件备份在 %s\n", from, CurrentVersion, backupPath)
+		}
+		migrated = true
+	}
 	if cfg.Version == "" {
-		cfg.Version = "1.0"
+		cfg.Version = CurrentVersion
 	}
 
-	return &cfg, nil
+	if err := cfg.loadIncludes(); err != nil {
+		return nil, false, err
+	}
+	for i := range cfg.Servers {
+		cfg.Servers[i] = cfg.resolveServer(cfg.Servers[i])
+	}
+	return cfg, migrated, nil
 }
 
-// Save 保存配置文件
+// Save 保存配置文件（持有配置锁期间写入）
+// 覆盖整个配置文件，基于较早读取的配置修改时请使用 Update，避免覆盖其他 gssh 进程的修改
 func Save(cfg *Config) error {
+	unlock, err := Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return save(cfg)
+}
+
+// save 备份并写入配置文件，来自服务器文件的服务器写回各自的文件，调用方需持有配置锁
+func save(cfg *Config) error {
 	configPath, err := GetConfigPath()
 	if err != nil {
 		return err
 	}
 
 	// 备份现有配置
-	if _, err := os.Stat(configPath); err == nil {
-		backupPath, err := GetBackupPath()
-		if err == nil {
-			data, _ := os.ReadFile(configPath)
-			os.WriteFile(backupPath, data, 0644)
+	if data, err := os.ReadFile(configPath); err == nil {
+		if backupPath, err := GetBackupPath(); err == nil {
+			util.WriteFileAtomic(backupPath, data, 0600)
 		}
 	}
 
-	data, err := yaml.Marshal(cfg)
+	// 启用保险库时，写入前加密所有明文密码
+	if err := sealSecrets(cfg); err != nil {
+		return fmt.Errorf("加密密码失败: %w", err)
+	}
+
+	main := *cfg
+	main.Servers = cfg.storedServers(cfg.mainServers())
+	data, err := yaml.Marshal(&main)
 	if err != nil {
 		return fmt.Errorf("序列化配置失败: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	if err := util.WriteFileAtomic(configPath, data, 0600); err != nil {
 		return fmt.Errorf("写入配置文件失败: %w", err)
 	}
+	if err := cfg.saveIncludes(); err != nil {
+		return err
+	}
+
+	// 配置已保存，导出的 ssh_config 生成失败只提示不影响保存结果
+	if cfg.Export.SSHConfig {
+		if err := WriteSSHConfigExport(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "警告: 更新导出的 ssh_config 失败: %v\n", err)
+		}
+	}
 
 	return nil
 }
@@ -73,7 +158,7 @@ func Save(cfg *Config) error {
 // NewDefaultConfig 创建默认配置
 func NewDefaultConfig() *Config {
 	return &Config{
-		Version: "1.0",
+		Version: CurrentVersion,
 		Sync: SyncConfig{
 			Enabled:  false,
 			Type:     "ssh",
@@ -83,7 +168,7 @@ func NewDefaultConfig() *Config {
 	}
 }
 
-// AddServer 添加服务器
+// AddServer 添加服务器，未设置的字段使用 defaults 和模板中的值
 func (c *Config) AddServer(server Server) error {
 	// 检查名称是否已存在
 	for _, s := range c.Servers {
@@ -92,10 +177,8 @@ func (c *Config) AddServer(server Server) error {
 		}
 	}
 
-	// 设置默认值
-	if server.Port == 0 {
-		server.Port = 22
-	}
+	// 未设置的字段使用 defaults / 模板中的值，都没有设置认证类型时为 auto
+	server = c.resolveServer(server)
 	if server.Auth.Type == "" {
 		server.Auth.Type = "auto"
 	}
@@ -104,6 +187,47 @@ func (c *Config) AddServer(server Server) error {
 	}
 
 	c.Servers = append(c.Servers, server)
+
+	// 检查新服务器的跳板机引用
+	if _, err := c.ResolveJumpChain(server.Name); err != nil {
+		c.Servers = c.Servers[:len(c.Servers)-1]
+		return err
+	}
+	return nil
+}
+
+// ReplaceServer 用 server 替换名为 name 的服务器（可以改名），保持原来的位置和所在的文件
+func (c *Config) ReplaceServer(name string, server Server) error {
+	index := -1
+	for i, s := range c.Servers {
+		if s.Name == name {
+			index = i
+		} else if s.Name == server.Name {
+			return fmt.Errorf("服务器名称 '%s' 已存在", server.Name)
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("服务器 '%s' 不存在", name)
+	}
+
+	// 未设置的字段使用 defaults / 模板中的值，都没有设置认证类型时为 auto
+	server = c.resolveServer(server)
+	if server.Auth.Type == "" {
+		server.Auth.Type = "auto"
+	}
+	if server.CreatedAt == "" {
+		server.CreatedAt = time.Now().Format(time.RFC3339)
+	}
+	server.Source = c.Servers[index].Source
+
+	old := c.Servers[index]
+	c.Servers[index] = server
+
+	// 检查修改后的跳板机引用
+	if _, err := c.ResolveJumpChain(server.Name); err != nil {
+		c.Servers[index] = old
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lockTimeout 等待其他 gssh 进程释放配置锁的最长时间
+	lockTimeout = 30 * time.Second
+	// lockRetryInterval 等待配置锁时的重试间隔
+	lockRetryInterval = 50 * time.Millisecond
+	// lockStaleAfter 无法确认持有者是否存活的锁（其他主机或内容不完整）超过该时间视为残留
+	lockStaleAfter = 2 * time.Minute
+)
+
+// processLock 同一进程内的互斥（例如界面与后台自动同步），锁文件只负责进程之间的互斥
+var processLock sync.Mutex
+
+// ErrUnchanged Update 的回调返回该错误时不保存配置，Update 返回 nil
+var ErrUnchanged = errors.New("配置未修改")
+
+// GetLockPath 获取配置锁文件路径
+func GetLockPath() (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return configPath + ".lock", nil
+}
+
+// Lock 获取配置文件的写锁（建议锁，只约束 gssh 自身），返回释放函数
+// 锁文件记录持有者的 PID、主机名、加锁时间和随机令牌；持有者进程已退出或锁已过期时视为残留并清除
+func Lock() (func(), error) {
+	lockPath, err := GetLockPath()
+	if err != nil {
+		return nil, err
+	}
+
+	processLock.Lock()
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		token := newLockToken()
+		acquired, err := createLock(lockPath, token)
+		if err != nil {
+			processLock.Unlock()
+			return nil, fmt.Errorf("创建配置锁失败: %w", err)
+		}
+		if acquired {
+			return func() {
+				releaseLock(lockPath, token)
+				processLock.Unlock()
+			}, nil
+		}
+
+		if holder, data, stale := staleLock(lockPath); stale {
+			if data != nil && breakStaleLock(lockPath, data) {
+				fmt.Fprintf(os.Stderr, "警告: 清除残留的配置锁（%s）\n", holder)
+			}
+			continue
+		} else if time.Now().After(deadline) {
+			processLock.Unlock()
+			return nil, fmt.Errorf("配置文件正被其他 gssh 进程修改（%s），请稍后重试；如果确认没有其他 gssh 在运行，可以删除 %s", holder, lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// newLockToken 生成区分每次加锁的随机令牌
+func newLockToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// createLock 创建锁文件，锁已被其他进程持有时返回 false
+func createLock(lockPath, token string) (bool, error) {
+	f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	host, _ := os.Hostname()
+	fmt.Fprintf(f, "%d %s %d %s\n", os.Getpid(), host, time.Now().Unix(), token)
+	return true, nil
+}
+
+// releaseLock 释放锁：只删除令牌一致的锁文件（锁被当作残留清除后可能已属于其他进程）
+func releaseLock(lockPath, token string) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return
+	}
+	if fields := strings.Fields(string(data)); len(fields) >= 4 && fields[3] == token {
+		os.Remove(lockPath)
+	}
+}
+
+// breakStaleLock 清除残留的锁：先重命名为唯一的名称再检查内容
+// 多个进程同时清除时只有一个能重命名成功；如果重命名走的已经是其他进程新加的锁则放回原处
+// 返回是否清除了残留的锁
+func breakStaleLock(lockPath string, stale []byte) bool {
+	moved := lockPath + ".stale-" + newLockToken()
+	if err := os.Rename(lockPath, moved); err != nil {
+		return false
+	}
+	defer os.Remove(moved)
+
+	if data, err := os.ReadFile(moved); err != nil || !bytes.Equal(data, stale) {
+		os.Link(moved, lockPath)
+		return false
+	}
+	return true
+}
+
+// staleLock 读取锁文件，判断是否为残留的锁，返回持有者的描述和锁文件内容
+// 锁已被释放时返回 stale 为 true、内容为 nil，直接重新加锁
+func staleLock(lockPath string) (string, []byte, bool) {
+	info, err := os.Stat(lockPath)
+	if err != nil {
+		return "", nil, os.IsNotExist(err)
+	}
+	age := time.Since(info.ModTime())
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "", nil, os.IsNotExist(err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		// 持有者可能正在写入锁文件
+		return "内容不完整", data, age > lockStaleAfter
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return "内容无效", data, true
+	}
+	holder := fmt.Sprintf("PID %d@%s", pid, fields[1])
+
+	if host, _ := os.Hostname(); fields[1] == host {
+		return holder, data, !processAlive(pid)
+	}
+	// 其他主机（例如共享的家目录）无法检查进程，只按时间判断
+	return holder, data, age > lockStaleAfter
+}
+
+// Update 在配置锁内重新读取配置，调用 fn 修改后保存，返回保存后的配置
+// 用于读-改-写：避免多个 gssh 进程同时修改时互相覆盖；fn 返回 ErrUnchanged 时不保存
+func Update(fn func(cfg *Config) error) (*Config, error) {
+	unlock, err := Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(cfg); err != nil {
+		if errors.Is(err, ErrUnchanged) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err := save(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion 当前 gssh 写入的配置文件格式版本
+// 修改配置格式时递增版本号，并在 migrations 末尾追加从上一个版本迁移的步骤
+const CurrentVersion = "1.3"
+
+// baseVersion 没有 version 字段的配置文件视为最早的格式
+const baseVersion = "1.0"
+
+// migration 把配置文件从 from 版本升级到 to 版本
+// 迁移直接修改 YAML 节点树，旧格式不一定能解析为当前的 Config 结构
+type migration struct {
+	from, to string
+	apply    func(root *yaml.Node) error
+}
+
+// migrations 按版本顺序排列，每一步的 to 是下一步的 from
+var migrations = []migration{
+	{from: "1.0", to: "1.1", apply: migrateV1_1},
+	{from: "1.1", to: "1.2", apply: migrateV1_2},
+	{from: "1.2", to: "1.3", apply: migrateV1_3},
+}
+
+// migrateConfig 将配置文件升级到 CurrentVersion，返回升级后的内容和原始版本
+// 版本已是最新时原样返回；文件来自更新版本的 gssh 时拒绝加载，避免丢失不认识的字段
+func migrateConfig(data []byte) (*yaml.Node, string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, "", fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	root := documentRoot(&doc)
+	if root == nil {
+		// 空文件
+		return &doc, CurrentVersion, nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, "", fmt.Errorf("配置文件顶层应为映射")
+	}
+
+	from := baseVersion
+	if v := mappingValue(root, "version"); v != nil && v.Value != "" {
+		from = v.Value
+	}
+	newer, err := versionNewer(from, CurrentVersion)
+	if err != nil {
+		return nil, "", err
+	}
+	if newer {
+		return nil, "", fmt.Errorf("配置文件版本 %s 高于当前 gssh 支持的版本 %s，可能由更新版本的 gssh 写入，请升级 gssh 后再使用", from, CurrentVersion)
+	}
+
+	version := from
+	for _, m := range migrations {
+		if m.from != version {
+			continue
+		}
+		if err := m.apply(root); err != nil {
+			return nil, "", fmt.Errorf("配置文件从版本 %s 升级到 %s 失败: %w", m.from, m.to, err)
+		}
+		version = m.to
+	}
+	if version != CurrentVersion {
+		return nil, "", fmt.Errorf("无法将配置文件从版本 %s 升级到 %s", from, CurrentVersion)
+	}
+	setMappingValue(root, "version", CurrentVersion)
+	return &doc, from, nil
+}
+
+// migrateV1_1 1.0 → 1.1
+//   - auth.type 为空的服务器按连接时的兜底逻辑明确写为 auto（配置了 identity_file）或 password
+//   - 手写的 tags / jump 为单个字符串时（例如 tags: web, prod）转换为列表
+//   - sync.type 为空时写为 ssh（最早只支持 SSH 方式同步）
+func migrateV1_1(root *yaml.Node) error {
+	if servers := mappingValue(root, "servers"); servers != nil && servers.Kind == yaml.SequenceNode {
+		for _, server := range servers.Content {
+			if server.Kind != yaml.MappingNode {
+				continue
+			}
+			scalarToList(server, "tags")
+			scalarToList(server, "jump")
+
+			auth := mappingValue(server, "auth")
+			if auth == nil {
+				auth = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				setMappingNode(server, "auth", auth)
+			}
+			if auth.Kind != yaml.MappingNode {
+				continue
+			}
+			if t := mappingValue(auth, "type"); t == nil || t.Value == "" {
+				authType := "password"
+				if f := mappingValue(auth, "identity_file"); f != nil && f.Value != "" {
+					authType = "auto"
+				}
+				setMappingValue(auth, "type", authType)
+			}
+		}
+	}
+
+	if sync := mappingValue(root, "sync"); sync != nil && sync.Kind == yaml.MappingNode {
+		if t := mappingValue(sync, "type"); t == nil || t.Value == "" {
+			setMappingValue(sync, "type", "ssh")
+		}
+	}
+	return nil
+}
+
+// migrateV1_2 1.1 → 1.2
+//   - 删除以前保存时写入的 port: 0（表示默认端口 22），之后 port 为 0 视为配置错误
+func migrateV1_2(root *yaml.Node) error {
+	servers := mappingValue(root, "servers")
+	if servers == nil || servers.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, server := range servers.Content {
+		if server.Kind != yaml.MappingNode {
+			continue
+		}
+		if port := mappingValue(server, "port"); port != nil && port.Kind == yaml.ScalarNode && port.Value == "0" {
+			deleteMappingKey(server, "port")
+		}
+	}
+	return nil
+}
+
+// migrateV1_3 1.2 → 1.3
+//   - 新增 defaults / templates，服务器省略的字段从中继承；已有内容不需要修改，
+//     提升版本号使不支持模板的旧版本 gssh 拒绝加载，而不是忽略继承的值
+func migrateV1_3(root *yaml.Node) error {
+	return nil
+}
+
+// scalarToList 把映射中 key 对应的字符串（逗号分隔）转换为列表，空字符串转换为空列表
+func scalarToList(m *yaml.Node, key string) {
+	v := mappingValue(m, key)
+	if v == nil || v.Kind != yaml.ScalarNode || v.Tag == "!!null" {
+		return
+	}
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, item := range strings.Split(v.Value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+		}
+	}
+	setMappingNode(m, key, list)
+}
+
+// documentRoot 返回 YAML 文档的顶层节点，空文档返回 nil
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// mappingValue 返回映射中 key 对应的值节点，不存在时返回 nil
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue 设置映射中 key 对应的字符串值
+func setMappingValue(m *yaml.Node, key, value string) {
+	setMappingNode(m, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+// setMappingNode 设置映射中 key 对应的值节点，不存在时追加到末尾
+func setMappingNode(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// deleteMappingKey 删除映射中的 key
+func deleteMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// versionNewer 判断版本 a 是否高于版本 b（形如 1.1 的数字版本）
+func versionNewer(a, b string) (bool, error) {
+	pa, err := parseVersion(a)
+	if err != nil {
+		return false, err
+	}
+	pb, err := parseVersion(b)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x > y, nil
+		}
+	}
+	return false, nil
+}
+
+// parseVersion 解析形如 1.1 的版本号
+func parseVersion(v string) ([]int, error) {
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("无法识别的配置文件版本 %q", v)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// backupBeforeMigration 将升级前的文件保存为 <文件>.v<版本>.backup，例如 config.yaml.v1.0.backup
+// created 表示本次新建了备份；已存在的备份不覆盖，保留最早的原始文件
+func backupBeforeMigration(path, version string, data []byte) (backupPath string, created bool, err error) {
+	backupPath = path + ".v" + version + ".backup"
+	if _, err := os.Stat(backupPath); err == nil {
+		return backupPath, false, nil
+	}
+	if err := util.WriteFileAtomic(backupPath, data, 0600); err != nil {
+		return "", false, fmt.Errorf("备份升级前的配置失败: %w", err)
+	}
+	return backupPath, true, nil
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// migrateToConfig 升级配置内容并解析为 Config，返回原始版本
+func migrateToConfig(t *testing.T, data string) (*Config, string) {
+	t.Helper()
+	doc, from, err := migrateConfig([]byte(data))
+	if err != nil {
+		t.Fatalf("升级失败: %v", err)
+	}
+	var cfg Config
+	if err := doc.Decode(&cfg); err != nil {
+		t.Fatalf("解析升级后的配置失败: %v", err)
+	}
+	return &cfg, from
+}
+
+func TestMigrateFromV1_0(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		tags     []string
+		jump     []string
+		authType string
+		syncType string
+	}{
+		{
+			name:     "字符串形式的 tags 和 jump",
+			data:     "servers:\n  - name: web\n    hostname: 10.0.0.1\n    tags: web, prod\n    jump: bastion\n    auth:\n      type: key\n",
+			tags:     []string{"web", "prod"},
+			jump:     []string{"bastion"},
+			authType: "key",
+		},
+		{
+			name:     "空字符串 tags 转换为空列表",
+			data:     "servers:\n  - name: web\n    hostname: 10.0.0.1\n    tags: \"\"\n    auth:\n      type: password\n",
+			authType: "password",
+		},
+		{
+			name:     "列表形式保持不变",
+			data:     "servers:\n  - name: web\n    hostname: 10.0.0.1\n    tags: [a, b]\n    jump: [j1, j2]\n    auth:\n      type: key\n",
+			tags:     []string{"a", "b"},
+			jump:     []string{"j1", "j2"},
+			authType: "key",
+		},
+		{
+			name:     "auth.type 为空且有密钥时为 auto",
+			data:     "servers:\n  - name: web\n    hostname: 10.0.0.1\n    auth:\n      identity_file: ~/.ssh/id_ed25519\n",
+			authType: "auto",
+		},
+		{
+			name:     "auth.type 为空且没有密钥时为 password",
+			data:     "servers:\n  - name: web\n    hostname: 10.0.0.1\n    auth:\n      type: \"\"\n",
+			authType: "password",
+		},
+		{
+			name:     "缺少 auth 时补充",
+			data:     "servers:\n  - name: web\n    hostname: 10.0.0.1\n",
+			authType: "password",
+		},
+		{
+			name:     "sync.type 缺失时为 ssh",
+			data:     "sync:\n  enabled: true\n  ssh_host: backup.example.com\nservers:\n  - name: web\n    hostname: 10.0.0.1\n    auth:\n      type: key\n",
+			authType: "key",
+			syncType: "ssh",
+		},
+		{
+			name:     "sync.type 已设置时不修改",
+			data:     "sync:\n  type: git\nservers:\n  - name: web\n    hostname: 10.0.0.1\n    auth:\n      type: key\n",
+			authType: "key",
+			syncType: "git",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, from := migrateToConfig(t, tt.data)
+			if from != "1.0" {
+				t.Errorf("原始版本 = %q，期望 1.0", from)
+			}
+			if cfg.Version != CurrentVersion {
+				t.Errorf("升级后版本 = %q，期望 %s", cfg.Version, CurrentVersion)
+			}
+			if len(cfg.Servers) != 1 {
+				t.Fatalf("服务器数量 = %d，期望 1", len(cfg.Servers))
+			}
+			s := cfg.Servers[0]
+			if !slices.Equal(s.Tags, tt.tags) {
+				t.Errorf("tags = %q，期望 %q", s.Tags, tt.tags)
+			}
+			if !slices.Equal(s.Jump, tt.jump) {
+				t.Errorf("jump = %q，期望 %q", s.Jump, tt.jump)
+			}
+			if s.Auth.Type != tt.authType {
+				t.Errorf("auth.type = %q，期望 %q", s.Auth.Type, tt.authType)
+			}
+			if cfg.Sync.Type != tt.syncType {
+				t.Errorf("sync.type = %q，期望 %q", cfg.Sync.Type, tt.syncType)
+			}
+		})
+	}
+}
+
+func TestMigrateFromV1_1(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		port    int
+		hasPort bool
+	}{
+		{
+			name: "port 为 0 时删除",
+			data: "version: \"1.1\"\nservers:\n  - name: web\n    hostname: 10.0.0.1\n    port: 0\n    auth:\n      type: key\n",
+		},
+		{
+			name:    "非 0 端口保留",
+			data:    "version: \"1.1\"\nservers:\n  - name: web\n    hostname: 10.0.0.1\n    port: 2222\n    auth:\n      type: key\n",
+			port:    2222,
+			hasPort: true,
+		},
+		{
+			name: "1.1 不再做 1.0 的转换",
+			data: "version: \"1.1\"\nservers:\n  - name: web\n    hostname: 10.0.0.1\n    auth:\n      type: key\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, from, err := migrateConfig([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("升级失败: %v", err)
+			}
+			if from != "1.1" {
+				t.Errorf("原始版本 = %q，期望 1.1", from)
+			}
+			server := mappingValue(documentRoot(doc), "servers").Content[0]
+			if got := mappingValue(server, "port") != nil; got != tt.hasPort {
+				t.Errorf("是否保留 port = %v，期望 %v", got, tt.hasPort)
+			}
+
+			var cfg Config
+			if err := doc.Decode(&cfg); err != nil {
+				t.Fatalf("解析升级后的配置失败: %v", err)
+			}
+			if cfg.Servers[0].Port != tt.port {
+				t.Errorf("port = %d，期望 %d", cfg.Servers[0].Port, tt.port)
+			}
+		})
+	}
+}
+
+func TestMigrateCurrentVersionUnchanged(t *testing.T) {
+	data := "version: \"" + CurrentVersion + "\"\nservers:\n  - name: web\n    hostname: 10.0.0.1\n    port: 0\n"
+	cfg, from := migrateToConfig(t, data)
+	if from != CurrentVersion {
+		t.Errorf("原始版本 = %q，期望 %s", from, CurrentVersion)
+	}
+	if cfg.Servers[0].Auth.Type != "" {
+		t.Errorf("当前版本的配置不应被修改，auth.type = %q", cfg.Servers[0].Auth.Type)
+	}
+}
+
+func TestMigrateRejectsVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{name: "更新的版本", version: "1.4", want: "高于当前 gssh 支持的版本"},
+		{name: "更新的主版本", version: "2.0", want: "高于当前 gssh 支持的版本"},
+		{name: "无法识别的版本", version: "v1", want: "无法识别的配置文件版本"},
+		{name: "没有迁移路径的版本", version: "1.0.5", want: "无法将配置文件从版本 1.0.5 升级"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "version: \"" + tt.version + "\"\nservers: []\n"
+			_, _, err := migrateConfig([]byte(data))
+			if err == nil {
+				t.Fatalf("版本 %s 应被拒绝", tt.version)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误 = %q，期望包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupBeforeMigrationKeepsExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	backupPath, created, err := backupBeforeMigration(path, "1.0", []byte("original"))
+	if err != nil {
+		t.Fatalf("备份失败: %v", err)
+	}
+	if !created {
+		t.Error("首次备份应新建备份文件")
+	}
+	if want := path + ".v1.0.backup"; backupPath != want {
+		t.Errorf("备份路径 = %q，期望 %q", backupPath, want)
+	}
+
+	again, created, err := backupBeforeMigration(path, "1.0", []byte("modified"))
+	if err != nil {
+		t.Fatalf("再次备份失败: %v", err)
+	}
+	if created {
+		t.Error("备份已存在时不应重新创建")
+	}
+	if again != backupPath {
+		t.Errorf("备份路径 = %q，期望 %q", again, backupPath)
+	}
+
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		t.Fatalf("读取备份失败: %v", err)
+	}
+	if string(data) != "original" {
+		t.Errorf("备份内容 = %q，已存在的备份不应被覆盖", data)
+	}
+}
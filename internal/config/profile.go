@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultSyncTarget sync 配置对应的同步目标名称
+const DefaultSyncTarget = "default"
+
+// profileNamePattern sync profile 名称的格式（同时用于基线快照的文件名）
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Matches 判断服务器是否符合 profile 的筛选条件
+func (p *SyncProfile) Matches(s *Server) bool {
+	if len(p.Groups) == 0 && len(p.Tags) == 0 {
+		return true
+	}
+	for _, g := range p.Groups {
+		if s.Group == g {
+			return true
+		}
+	}
+	for _, want := range p.Tags {
+		for _, tag := range s.Tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SyncOwner 返回服务器所属的同步目标：第一个匹配的 sync profile，都不匹配时为 default（sync 配置）
+func (c *Config) SyncOwner(s *Server) string {
+	for i := range c.SyncProfiles {
+		if c.SyncProfiles[i].Matches(s) {
+			return c.SyncProfiles[i].Name
+		}
+	}
+	return DefaultSyncTarget
+}
+
+// SyncTarget 按名称返回同步目标的配置，default 或空名称返回 sync 配置
+func (c *Config) SyncTarget(name string) (*SyncConfig, error) {
+	if name == "" || name == DefaultSyncTarget {
+		return &c.Sync, nil
+	}
+	for i := range c.SyncProfiles {
+		if c.SyncProfiles[i].Name == name {
+			return &c.SyncProfiles[i].SyncConfig, nil
+		}
+	}
+	return nil, fmt.Errorf("sync profile 不存在: %s", name)
+}
+
+// ValidateSyncProfiles 检查 sync profile 的名称：不能为空、不能重复，也不能使用保留名称 default
+func (c *Config) ValidateSyncProfiles() error {
+	seen := make(map[string]bool)
+	for i, p := range c.SyncProfiles {
+		switch {
+		case p.Name == "":
+			return fmt.Errorf("sync_profiles 第 %d 项缺少 name", i+1)
+		case p.Name == DefaultSyncTarget:
+			return fmt.Errorf("sync profile 不能命名为 %s（保留给 sync 配置）", DefaultSyncTarget)
+		case !profileNamePattern.MatchString(p.Name):
+			return fmt.Errorf("sync profile 名称只能包含字母、数字、'.'、'_' 和 '-': %s", p.Name)
+		case seen[p.Name]:
+			return fmt.Errorf("sync profile 名称重复: %s", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fijdemon/gssh/internal/util"
+	"github.com/fijdemon/gssh/internal/vault"
+)
+
+// sealSecrets 启用保险库时加密配置中所有明文密码（已加密的保持不变）
+func sealSecrets(cfg *Config) error {
+	v, err := vault.Load()
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+
+	seal := func(value *string) error {
+		if *value == "" || vault.IsSealed(*value) {
+			return nil
+		}
+		sealed, err := v.Seal(*value)
+		if err != nil {
+			return err
+		}
+		*value = sealed
+		return nil
+	}
+
+	for _, secret := range cfg.Secrets() {
+		if err := seal(secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Secrets 返回配置中所有密码字段的指针（服务器密码，以及 sync 和各 sync profile 的密码、Token 与同步加密口令）
+func (c *Config) Secrets() []*string {
+	secrets := make([]*string, 0, len(c.Servers)+3*(len(c.SyncProfiles)+1))
+	for i := range c.Servers {
+		secrets = append(secrets, &c.Servers[i].Auth.Password)
+	}
+	secrets = append(secrets, &c.Sync.Password, &c.Sync.Token, &c.Sync.Encryption.Passphrase)
+	for i := range c.SyncProfiles {
+		p := &c.SyncProfiles[i]
+		secrets = append(secrets, &p.Password, &p.Token, &p.Encryption.Passphrase)
+	}
+	return secrets
+}
+
+// RevealServers 返回密码已解密的服务器列表副本（用于推送给其他客户端）
+func RevealServers(servers []Server) ([]Server, error) {
+	revealed := make([]Server, len(servers))
+	copy(revealed, servers)
+	for i := range revealed {
+		password, err := vault.Reveal(revealed[i].Auth.Password)
+		if err != nil {
+			return nil, err
+		}
+		revealed[i].Auth.Password = password
+	}
+	return revealed, nil
+}
+
+// Rekey 更换保险库密钥：用 newVault 重新加密配置和所有同步基线快照中的密码，然后用 newVault 替换保险库
+// priv 为当前保险库 v 的私钥；返回保存后的配置和重新加密的配置密码数量
+// 重新加密期间持有配置锁，避免其他 gssh 进程用旧公钥写入密码
+func Rekey(v *vault.Vault, priv *[32]byte, newVault *vault.Vault) (*Config, int, error) {
+	reseal := func(secret *string) error {
+		plain := *secret
+		var err error
+		if vault.IsSealed(plain) {
+			if plain, err = v.Open(plain, priv); err != nil {
+				return err
+			}
+		}
+		*secret, err = newVault.Seal(plain)
+		return err
+	}
+
+	vaultPath, err := vault.GetVaultPath()
+	if err != nil {
+		return nil, 0, err
+	}
+	backupPath := vaultPath + ".old"
+
+	count := 0
+	cfg, err := Update(func(cfg *Config) error {
+		// 先在内存中用新密钥重新加密，全部成功后再落盘
+		for _, secret := range cfg.Secrets() {
+			if *secret == "" {
+				continue
+			}
+			if err := reseal(secret); err != nil {
+				return err
+			}
+			count++
+		}
+
+		// 同步基线同样用保险库加密，不重新加密的话之后的拉取、推送都无法解密
+		paths, err := syncBasePaths()
+		if err != nil {
+			return err
+		}
+		snapshots := make([][]Server, len(paths))
+		for i, path := range paths {
+			servers, err := LoadSnapshot(path)
+			if err != nil {
+				return err
+			}
+			for j := range servers {
+				if servers[j].Auth.Password == "" {
+					continue
+				}
+				if err := reseal(&servers[j].Auth.Password); err != nil {
+					return fmt.Errorf("重新加密 %s 失败: %w", path, err)
+				}
+			}
+			snapshots[i] = servers
+		}
+
+		// 旧保险库先备份，配置保存成功后再删除，避免中途失败导致密码无法解密
+		oldData, err := os.ReadFile(vaultPath)
+		if err != nil {
+			return fmt.Errorf("读取保险库失败: %w", err)
+		}
+		if err := util.WriteFileAtomic(backupPath, oldData, 0600); err != nil {
+			return fmt.Errorf("备份保险库失败: %w", err)
+		}
+		if err := newVault.Save(); err != nil {
+			return err
+		}
+		// 基线中的密码都已用新密钥加密，保存时不会再次加密
+		for i, path := range paths {
+			if err := SaveSnapshot(path, snapshots[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if _, statErr := os.Stat(backupPath); statErr == nil {
+			return nil, 0, fmt.Errorf("保存配置失败（旧保险库已备份到 %s）: %w", backupPath, err)
+		}
+		return nil, 0, err
+	}
+	os.Remove(backupPath)
+	return cfg, count, nil
+}
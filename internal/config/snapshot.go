@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fijdemon/gssh/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// GetSyncBasePath 获取同步目标的基线快照路径（最后一次成功同步时双方共同的服务器列表，用于三方合并）
+// sync 的基线为 sync_base.yaml，sync profile 的基线为 sync_base.<name>.yaml
+func GetSyncBasePath(target string) (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	name := "sync_base.yaml"
+	if target != "" && target != DefaultSyncTarget {
+		name = "sync_base." + target + ".yaml"
+	}
+	return filepath.Join(filepath.Dir(configPath), name), nil
+}
+
+// syncBasePaths 返回所有已存在的同步基线快照路径（包括已删除的 sync profile 留下的）
+func syncBasePaths() ([]string, error) {
+	path, err := GetSyncBasePath(DefaultSyncTarget)
+	if err != nil {
+		return nil, err
+	}
+	return filepath.Glob(filepath.Join(filepath.Dir(path), "sync_base*.yaml"))
+}
+
+// LoadSnapshot 读取服务器快照，文件不存在时返回 nil
+func LoadSnapshot(path string) ([]Server, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+	}
+
+	var snapshot Config
+	if err := yaml.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	if snapshot.Servers == nil {
+		snapshot.Servers = []Server{}
+	}
+	return snapshot.Servers, nil
+}
+
+// SaveSnapshot 保存服务器快照，启用保险库时密码同样加密保存
+func SaveSnapshot(path string, servers []Server) error {
+	snapshot := &Config{Version: CurrentVersion, Servers: make([]Server, len(servers))}
+	copy(snapshot.Servers, servers)
+	if err := sealSecrets(snapshot); err != nil {
+		return fmt.Errorf("加密密码失败: %w", err)
+	}
+
+	data, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("序列化快照失败: %w", err)
+	}
+	if err := util.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/util"
+)
+
+// sshConfigHeader 生成的 ssh_config 文件头
+const sshConfigHeader = "# 由 gssh 生成，请勿手动修改，修改会在下次保存配置时被覆盖\n# 通过 gssh export ssh-config --write 维护\n"
+
+// GetSSHConfigExportPath 获取自动导出的 ssh_config 文件路径
+func (c *Config) GetSSHConfigExportPath() (string, error) {
+	if c.Export.Path != "" {
+		return expandPath(c.Export.Path)
+	}
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "ssh_config"), nil
+}
+
+// ExportServers 返回按分组/标签筛选后需要导出的服务器，被引用的跳板机即使不匹配也会一并导出
+func (c *Config) ExportServers(group string, tags []string) []Server {
+	selected := make(map[string]bool)
+	var mark func(name string)
+	mark = func(name string) {
+		if selected[name] {
+			return
+		}
+		s, err := c.GetServer(name)
+		if err != nil {
+			return
+		}
+		selected[name] = true
+		for _, hop := range s.Jump {
+			mark(strings.TrimSpace(hop))
+		}
+	}
+	for _, s := range c.FilterServers(tags, group) {
+		mark(s.Name)
+	}
+
+	// 保持配置文件中的顺序
+	var result []Server
+	for _, s := range c.Servers {
+		if selected[s.Name] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// RenderSSHConfig 将服务器渲染为 ssh_config 的 Host 段
+func RenderSSHConfig(servers []Server) string {
+	var b strings.Builder
+	b.WriteString(sshConfigHeader)
+
+	for _, s := range servers {
+		b.WriteString("\n")
+		if strings.ContainsAny(s.Name, " \t*?!,\"") {
+			fmt.Fprintf(&b, "# 跳过 %q：名称不能作为 Host 别名\n", s.Name)
+			continue
+		}
+
+		if s.Description != "" {
+			fmt.Fprintf(&b, "# %s\n", s.Description)
+		}
+		fmt.Fprintf(&b, "Host %s\n", s.Name)
+		fmt.Fprintf(&b, "    HostName %s\n", s.Hostname)
+		if s.User != "" {
+			fmt.Fprintf(&b, "    User %s\n", quoteSSHConfigValue(s.User))
+		}
+		fmt.Fprintf(&b, "    Port %d\n", s.GetPort())
+		if s.Auth.IdentityFile != "" && s.Auth.Type != "password" {
+			fmt.Fprintf(&b, "    IdentityFile %s\n", quoteSSHConfigValue(s.Auth.IdentityFile))
+		}
+		if s.Auth.Type == "password" {
+			b.WriteString("    PreferredAuthentications keyboard-interactive,password\n")
+		}
+
+		var jumps []string
+		for _, hop := range s.Jump {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				jumps = append(jumps, hop)
+			}
+		}
+		if len(jumps) > 0 {
+			fmt.Fprintf(&b, "    ProxyJump %s\n", strings.Join(jumps, ","))
+		}
+	}
+	return b.String()
+}
+
+// WriteSSHConfigExport 按导出设置重新生成 ssh_config 文件
+func WriteSSHConfigExport(cfg *Config) error {
+	path, err := cfg.GetSSHConfigExportPath()
+	if err != nil {
+		return err
+	}
+	data := RenderSSHConfig(cfg.ExportServers(cfg.Export.Group, cfg.Export.Tags))
+	if err := util.WriteFileAtomic(path, []byte(data), 0600); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// EnsureSSHConfigInclude 确保 ~/.ssh/config 开头包含生成文件的 Include，返回是否做了修改
+// Include 必须位于所有 Host 段之前才对所有主机生效
+func EnsureSSHConfigInclude(includePath string) (bool, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return false, fmt.Errorf("获取用户目录失败: %w", err)
+	}
+	sshDir := filepath.Join(homeDir, ".ssh")
+	userConfig := filepath.Join(sshDir, "config")
+
+	data, err := os.ReadFile(userConfig)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("读取 %s 失败: %w", userConfig, err)
+	}
+
+	// 尽量使用 ~ 形式，方便在多台机器间复制 ~/.ssh/config
+	display := includePath
+	if rel, err := filepath.Rel(homeDir, includePath); err == nil && !strings.HasPrefix(rel, "..") {
+		display = "~/" + filepath.ToSlash(rel)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && strings.EqualFold(fields[0], "include") {
+			for _, f := range fields[1:] {
+				if f == display || f == includePath {
+					return false, nil
+				}
+			}
+		}
+	}
+
+	if err := os.MkdirAll(sshDir, 0700); err != nil {
+		return false, fmt.Errorf("创建 %s 失败: %w", sshDir, err)
+	}
+
+	// 保留已有文件的权限；~/.ssh/config 是符号链接（例如由 dotfiles 管理）时写入链接指向的文件
+	perm := os.FileMode(0600)
+	if info, err := os.Stat(userConfig); err == nil {
+		perm = info.Mode().Perm()
+		if target, err := filepath.EvalSymlinks(userConfig); err == nil {
+			userConfig = target
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# gssh 管理的服务器\nInclude %s\n\n", display)
+	b.Write(data)
+	if err := util.WriteFileAtomic(userConfig, b.Bytes(), perm); err != nil {
+		return false, fmt.Errorf("写入 %s 失败: %w", userConfig, err)
+	}
+	return true, nil
+}
+
+// quoteSSHConfigValue 包含空白的值用双引号括起来
+func quoteSSHConfigValue(v string) string {
+	if strings.ContainsAny(v, " \t") {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// expandPath 展开路径开头的 ~
+func expandPath(p string) (string, error) {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户目录失败: %w", err)
+	}
+	return filepath.Join(homeDir, p[1:]), nil
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ServerTemplate defaults 和 templates 中可以设置的服务器字段，未设置的字段不覆盖上一层
+type ServerTemplate struct {
+	User  string       `yaml:"user,omitempty"`
+	Port  int          `yaml:"port,omitempty"`
+	Group string       `yaml:"group,omitempty"`
+	Tags  []string     `yaml:"tags,omitempty"` // 与 jump 一样整体覆盖上一层，不与上一层合并
+	Auth  TemplateAuth `yaml:"auth,omitempty"`
+	Jump  []string     `yaml:"jump,omitempty"`
+}
+
+// TemplateAuth 模板中的认证配置（不支持明文密码，请使用 password_cmd）
+type TemplateAuth struct {
+	Type          string `yaml:"type,omitempty"`
+	IdentityFile  string `yaml:"identity_file,omitempty"`
+	PasswordCmd   string `yaml:"password_cmd,omitempty"`
+	PassphraseCmd string `yaml:"passphrase_cmd,omitempty"`
+}
+
+// 字段值的来源
+const (
+	LayerServer   = "server"
+	LayerTemplate = "template"
+	LayerDefaults = "defaults"
+)
+
+// GetTemplate 获取模板
+func (c *Config) GetTemplate(name string) (*ServerTemplate, error) {
+	t, ok := c.Templates[name]
+	if !ok {
+		return nil, fmt.Errorf("模板 '%s' 不存在", name)
+	}
+	return &t, nil
+}
+
+// Inherited 依次合并 defaults 和模板，得到服务器未设置的字段继承的值；模板不存在时只使用 defaults
+func (c *Config) Inherited(template string) ServerTemplate {
+	layers := []ServerTemplate{c.Defaults}
+	if t, ok := c.Templates[template]; ok && template != "" {
+		layers = append(layers, t)
+	}
+
+	var result ServerTemplate
+	for _, l := range layers {
+		result.User = override(result.User, l.User)
+		if l.Port != 0 {
+			result.Port = l.Port
+		}
+		result.Group = override(result.Group, l.Group)
+		result.Auth.Type = override(result.Auth.Type, l.Auth.Type)
+		result.Auth.IdentityFile = override(result.Auth.IdentityFile, l.Auth.IdentityFile)
+		result.Auth.PasswordCmd = override(result.Auth.PasswordCmd, l.Auth.PasswordCmd)
+		result.Auth.PassphraseCmd = override(result.Auth.PassphraseCmd, l.Auth.PassphraseCmd)
+		if len(l.Tags) > 0 {
+			result.Tags = l.Tags
+		}
+		if len(l.Jump) > 0 {
+			result.Jump = l.Jump
+		}
+	}
+	return result
+}
+
+// resolveServer 按 defaults → 模板 → 服务器 的顺序得到服务器的实际配置
+// 服务器没有设置的字段使用继承的值；标签和跳板机是整体的列表，服务器设置了就不再使用继承的值
+func (c *Config) resolveServer(s Server) Server {
+	t := c.Inherited(s.Template)
+	s.User = override(t.User, s.User)
+	if s.Port == 0 {
+		s.Port = t.Port
+	}
+	s.Group = override(t.Group, s.Group)
+	s.Auth.Type = override(t.Auth.Type, s.Auth.Type)
+	s.Auth.IdentityFile = override(t.Auth.IdentityFile, s.Auth.IdentityFile)
+	s.Auth.PasswordCmd = override(t.Auth.PasswordCmd, s.Auth.PasswordCmd)
+	s.Auth.PassphraseCmd = override(t.Auth.PassphraseCmd, s.Auth.PassphraseCmd)
+	if len(s.Tags) == 0 {
+		s.Tags = slices.Clone(t.Tags)
+	}
+	if len(s.Jump) == 0 {
+		s.Jump = slices.Clone(t.Jump)
+	}
+	return s
+}
+
+// storedServer 返回写入配置文件时的服务器：与继承值相同的字段省略，保留 defaults / 模板的分层
+func (c *Config) storedServer(s Server) Server {
+	t := c.Inherited(s.Template)
+	if s.User == t.User {
+		s.User = ""
+	}
+	if s.Port == t.Port {
+		s.Port = 0
+	}
+	if s.Group == t.Group {
+		s.Group = ""
+	}
+	if slices.Equal(s.Tags, t.Tags) {
+		s.Tags = []string{}
+	}
+	if s.Auth.Type == t.Auth.Type {
+		s.Auth.Type = ""
+	}
+	if s.Auth.IdentityFile == t.Auth.IdentityFile {
+		s.Auth.IdentityFile = ""
+	}
+	if s.Auth.PasswordCmd == t.Auth.PasswordCmd {
+		s.Auth.PasswordCmd = ""
+	}
+	if s.Auth.PassphraseCmd == t.Auth.PassphraseCmd {
+		s.Auth.PassphraseCmd = ""
+	}
+	if slices.Equal(s.Jump, t.Jump) {
+		s.Jump = nil
+	}
+	return s
+}
+
+// ServerOverrides 返回服务器自己设置的字段（与继承值相同的字段为空），用于编辑服务器
+func (c *Config) ServerOverrides(s Server) Server {
+	return c.storedServer(s)
+}
+
+// storedServers 对服务器列表调用 storedServer
+func (c *Config) storedServers(servers []Server) []Server {
+	stored := make([]Server, len(servers))
+	for i, s := range servers {
+		stored[i] = c.storedServer(s)
+	}
+	return stored
+}
+
+// ServerLayers 返回服务器可继承的各字段的值来自哪一层（LayerServer / LayerTemplate / LayerDefaults）
+// 没有值的字段不包含在结果中
+func (c *Config) ServerLayers(s Server) map[string]string {
+	stored := c.storedServer(s)
+	var template ServerTemplate
+	if t, ok := c.Templates[s.Template]; ok && s.Template != "" {
+		template = t
+	}
+
+	layers := make(map[string]string)
+	set := func(field, value, own, fromTemplate string) {
+		switch {
+		case value == "":
+		case own != "":
+			layers[field] = LayerServer
+		case fromTemplate != "":
+			layers[field] = LayerTemplate
+		default:
+			layers[field] = LayerDefaults
+		}
+	}
+	portString := func(p int) string {
+		if p == 0 {
+			return ""
+		}
+		return fmt.Sprint(p)
+	}
+	set("user", s.User, stored.User, template.User)
+	set("port", portString(s.Port), portString(stored.Port), portString(template.Port))
+	set("group", s.Group, stored.Group, template.Group)
+	set("auth.type", s.Auth.Type, stored.Auth.Type, template.Auth.Type)
+	set("auth.identity_file", s.Auth.IdentityFile, stored.Auth.IdentityFile, template.Auth.IdentityFile)
+	set("auth.password_cmd", s.Auth.PasswordCmd, stored.Auth.PasswordCmd, template.Auth.PasswordCmd)
+	set("auth.passphrase_cmd", s.Auth.PassphraseCmd, stored.Auth.PassphraseCmd, template.Auth.PassphraseCmd)
+	setList := func(field string, value, own, fromTemplate []string) {
+		switch {
+		case len(value) == 0:
+		case len(own) > 0:
+			layers[field] = LayerServer
+		case len(fromTemplate) > 0:
+			layers[field] = LayerTemplate
+		default:
+			layers[field] = LayerDefaults
+		}
+	}
+	setList("tags", s.Tags, stored.Tags, template.Tags)
+	setList("jump", s.Jump, stored.Jump, template.Jump)
+	return layers
+}
+
+// override 上一层的值被非空的 value 覆盖
+func override(base, value string) string {
+	if value != "" {
+		return value
+	}
+	return base
+}
@@ -0,0 +1,330 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue 配置检查发现的问题，Line 为 0 表示无法定位到具体的行
+type Issue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Server  string `json:"server,omitempty"`
+	Message string `json:"message"`
+}
+
+// String 返回 file:line: message 形式的描述
+func (i Issue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", i.File, i.Message)
+}
+
+// issueHandler 处理加载配置时发现的问题，默认作为警告输出到标准错误
+var issueHandler = func(issue Issue) {
+	fmt.Fprintf(os.Stderr, "警告: %s\n", issue)
+}
+
+var (
+	issueMu  sync.Mutex
+	reported = map[Issue]bool{} // 已报告过的问题，后台同步、界面刷新等反复加载时不重复报告
+)
+
+// SetIssueHandler 设置加载配置时发现问题的处理方式（例如界面运行期间暂存，退出后再输出），返回恢复原处理方式的函数
+func SetIssueHandler(fn func(Issue)) (restore func()) {
+	issueMu.Lock()
+	defer issueMu.Unlock()
+	prev := issueHandler
+	issueHandler = fn
+	return func() {
+		issueMu.Lock()
+		defer issueMu.Unlock()
+		issueHandler = prev
+	}
+}
+
+// reportIssues 检查配置并报告本进程中还没有报告过的问题
+func reportIssues() {
+	issues, err := Validate()
+	if err != nil {
+		return
+	}
+	issueMu.Lock()
+	defer issueMu.Unlock()
+	for _, issue := range issues {
+		if !reported[issue] {
+			reported[issue] = true
+			issueHandler(issue)
+		}
+	}
+}
+
+// validAuthTypes 支持的认证类型，为空时连接时按兜底逻辑处理
+var validAuthTypes = map[string]bool{"": true, "auto": true, "key": true, "password": true}
+
+// serverEntry 配置文件中的一台服务器及其所在的位置
+type serverEntry struct {
+	file   string
+	node   *yaml.Node
+	server Server
+}
+
+// parsedFile 为检查而解析的配置文件
+type parsedFile struct {
+	root    *yaml.Node // 顶层映射，空文件时为 nil
+	cfg     Config
+	entries []serverEntry
+	issues  []Issue // 类型不匹配等解析问题
+}
+
+// Validate 检查主配置文件以及 conf.d / include 中的服务器文件，返回发现的问题
+// 不同文件之间重名的服务器同样会报告（只使用先读取的那一台）；文件无法解析时返回错误
+func Validate() ([]Issue, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	main, err := parseFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		main, err = &parsedFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	issues := main.issues
+	entries := main.entries
+	paths, missing, err := main.cfg.IncludePaths()
+	if err != nil {
+		return nil, err
+	}
+	for _, pattern := range missing {
+		line := 0
+		if main.root != nil {
+			line = keyLine(main.root, "include")
+		}
+		issues = append(issues, Issue{File: configPath, Line: line, Message: fmt.Sprintf("include 的 %s 没有匹配到任何文件", pattern)})
+	}
+	for _, path := range paths {
+		f, err := parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, f.issues...)
+		entries = append(entries, f.entries...)
+	}
+	if main.root != nil {
+		issues = append(issues, checkTemplates(configPath, main.root, &main.cfg)...)
+	}
+	issues = append(issues, checkServers(&main.cfg, entries)...)
+	return sortIssues(issues, append([]string{configPath}, paths...)...), nil
+}
+
+// ValidateFile 只检查指定的配置文件（不读取其中的 include），返回发现的问题
+// 文件中没有 defaults / templates 时（例如服务器文件）使用主配置中的模板
+// 旧版本的配置先在内存中升级（不写回）；文件无法解析时返回错误
+func ValidateFile(path string) ([]Issue, error) {
+	f, err := parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := f.issues
+	base := &f.cfg
+	if f.root != nil && (mappingValue(f.root, "defaults") != nil || mappingValue(f.root, "templates") != nil) {
+		issues = append(issues, checkTemplates(path, f.root, &f.cfg)...)
+	} else if configPath, err := GetConfigPath(); err == nil {
+		if main, err := parseFile(configPath); err == nil {
+			base = &main.cfg
+		}
+	}
+	issues = append(issues, checkServers(base, f.entries)...)
+	return sortIssues(issues, path), nil
+}
+
+// parseFile 读取并解析配置文件（或服务器文件），记录每台服务器对应的 YAML 节点
+// 类型不匹配（例如 port: abc）时 yaml 仍会解码其余字段，作为问题逐条报告后继续检查
+func parseFile(path string) (*parsedFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	doc, _, err := migrateConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w（%s）", err, path)
+	}
+
+	f := &parsedFile{root: documentRoot(doc)}
+	if err := doc.Decode(&f.cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+		for _, msg := range typeErr.Errors {
+			var line int
+			if _, err := fmt.Sscanf(msg, "line %d:", &line); err == nil {
+				msg = strings.TrimSpace(msg[strings.Index(msg, ":")+1:])
+			}
+			f.issues = append(f.issues, Issue{File: path, Line: line, Message: "类型错误: " + msg})
+		}
+	}
+	if f.root == nil {
+		return f, nil
+	}
+	servers := mappingValue(f.root, "servers")
+	if servers == nil || servers.Kind != yaml.SequenceNode || len(servers.Content) != len(f.cfg.Servers) {
+		return f, nil
+	}
+	for i, node := range servers.Content {
+		f.entries = append(f.entries, serverEntry{file: path, node: node, server: f.cfg.Servers[i]})
+	}
+	return f, nil
+}
+
+// checkTemplates 检查 defaults 和 templates 中的端口、认证类型和密钥文件
+func checkTemplates(file string, root *yaml.Node, cfg *Config) []Issue {
+	var issues []Issue
+	check := func(label string, node *yaml.Node, t ServerTemplate) {
+		add := func(line int, format string, args ...any) {
+			issues = append(issues, Issue{File: file, Line: line, Message: label + fmt.Sprintf(format, args...)})
+		}
+		if port := mappingValue(node, "port"); port != nil && port.Tag == "!!int" && (t.Port < 1 || t.Port > 65535) {
+			add(keyLine(node, "port"), " 的端口 %d 无效（应为 1-65535）", t.Port)
+		}
+		auth := mappingValue(node, "auth")
+		if auth == nil {
+			auth = node
+		}
+		if !validAuthTypes[t.Auth.Type] {
+			add(keyLine(auth, "type"), " 的认证类型 %q 未知（可选 auto、key、password）", t.Auth.Type)
+		}
+		if t.Auth.IdentityFile != "" && !fileExists(t.Auth.IdentityFile) {
+			add(keyLine(auth, "identity_file"), " 的密钥文件 %s 不存在或无法访问", t.Auth.IdentityFile)
+		}
+		if mappingValue(auth, "password") != nil {
+			add(keyLine(auth, "password"), " 不支持 password，请在服务器中配置或使用 password_cmd")
+		}
+	}
+
+	if node := mappingValue(root, "defaults"); node != nil && node.Kind == yaml.MappingNode {
+		check("defaults", node, cfg.Defaults)
+	}
+	if templates := mappingValue(root, "templates"); templates != nil && templates.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(templates.Content); i += 2 {
+			name, node := templates.Content[i].Value, templates.Content[i+1]
+			if node.Kind == yaml.MappingNode {
+				check(fmt.Sprintf("模板 '%s'", name), node, cfg.Templates[name])
+			}
+		}
+	}
+	return issues
+}
+
+// checkServers 逐台检查服务器：名称为空或重复、hostname 为空、端口超出范围、未知的认证类型、
+// 密钥文件不存在、引用的模板不存在以及跳板机引用错误；base 提供 defaults 和模板
+func checkServers(base *Config, entries []serverEntry) []Issue {
+	var issues []Issue
+	add := func(e serverEntry, line int, format string, args ...any) {
+		issues = append(issues, Issue{File: e.file, Line: line, Server: e.server.Name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// 与加载时一致：同一文件中重名的服务器都会读取，不同文件之间重名时只使用先读取的
+	cfg := Config{Defaults: base.Defaults, Templates: base.Templates}
+	first := make(map[string]serverEntry)
+	for _, e := range entries {
+		name := e.server.Name
+		prev, ok := first[name]
+		switch {
+		case name == "":
+			add(e, e.node.Line, "服务器缺少 name")
+		case !ok:
+			first[name] = e
+		case prev.file == e.file:
+			add(e, keyLine(e.node, "name"), "服务器名称 '%s' 重复（第一次出现在第 %d 行）", name, keyLine(prev.node, "name"))
+		default:
+			add(e, keyLine(e.node, "name"), "服务器名称 '%s' 与 %s:%d 重复，这台服务器不会被使用", name, prev.file, keyLine(prev.node, "name"))
+			continue
+		}
+		cfg.Servers = append(cfg.Servers, cfg.resolveServer(e.server))
+	}
+
+	for i, e := range entries {
+		s := e.server
+		label := s.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+
+		if s.Hostname == "" {
+			add(e, keyLine(e.node, "hostname"), "服务器 '%s' 的 hostname 为空", label)
+		}
+		if port := mappingValue(e.node, "port"); port != nil && port.Tag == "!!int" && (s.Port < 1 || s.Port > 65535) {
+			add(e, keyLine(e.node, "port"), "服务器 '%s' 的端口 %d 无效（应为 1-65535，省略时为 22）", label, s.Port)
+		}
+
+		auth := mappingValue(e.node, "auth")
+		if auth == nil {
+			auth = e.node
+		}
+		if !validAuthTypes[s.Auth.Type] {
+			add(e, keyLine(auth, "type"), "服务器 '%s' 的认证类型 %q 未知（可选 auto、key、password）", label, s.Auth.Type)
+		}
+		if s.Auth.IdentityFile != "" && !fileExists(s.Auth.IdentityFile) {
+			add(e, keyLine(auth, "identity_file"), "服务器 '%s' 的密钥文件 %s 不存在或无法访问", label, s.Auth.IdentityFile)
+		}
+		if s.Template != "" {
+			if _, err := cfg.GetTemplate(s.Template); err != nil {
+				add(e, keyLine(e.node, "template"), "服务器 '%s' 引用的%v", label, err)
+			}
+		}
+
+		if len(cfg.resolveServer(s).Jump) > 0 && first[s.Name].node == e.node {
+			if _, err := cfg.ResolveJumpChain(s.Name); err != nil {
+				add(e, keyLine(e.node, "jump"), "%v", err)
+			}
+		}
+	}
+	return issues
+}
+
+// fileExists 判断文件是否存在（支持 ~）
+func fileExists(path string) bool {
+	p, err := expandPath(path)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(p)
+	return err == nil
+}
+
+// sortIssues 按文件（files 的顺序）和行号排序
+func sortIssues(issues []Issue, files ...string) []Issue {
+	order := make(map[string]int, len(files))
+	for i, f := range files {
+		order[f] = i
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return order[issues[i].File] < order[issues[j].File]
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+// keyLine 返回映射中 key 所在的行，key 不存在（或由升级添加）时返回映射本身所在的行
+func keyLine(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key && m.Content[i].Line > 0 {
+			return m.Content[i].Line
+		}
+	}
+	return m.Line
+}
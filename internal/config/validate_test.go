@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoadReportsIssuesOnce(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path, err := GetConfigPath()
+	if err != nil {
+		t.Fatalf("获取配置路径失败: %v", err)
+	}
+	data := "version: \"" + CurrentVersion + "\"\nservers:\n  - name: web\n    hostname: 10.0.0.1\n    port: 70000\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+
+	var issues []Issue
+	restore := SetIssueHandler(func(issue Issue) { issues = append(issues, issue) })
+	defer restore()
+
+	// 反复加载时同一问题只报告一次
+	for range 3 {
+		if _, err := Load(); err != nil {
+			t.Fatalf("加载配置失败: %v", err)
+		}
+	}
+	if len(issues) != 1 {
+		t.Fatalf("报告的问题 = %v，期望只报告一次端口问题", issues)
+	}
+	if issues[0].File != path || issues[0].Line != 5 {
+		t.Errorf("问题位置 = %s:%d，期望 %s:5", issues[0].File, issues[0].Line, path)
+	}
+
+	// 新出现的问题仍会报告
+	if err := os.WriteFile(path, []byte(data+"  - name: web\n    hostname: 10.0.0.2\n"), 0600); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	if _, err := Load(); err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Errorf("报告的问题 = %v，期望新增重名问题", issues)
+	}
+}
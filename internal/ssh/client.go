@@ -1,17 +1,21 @@
 package ssh
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/fijdemon/gssh/internal/util"
+	"github.com/fijdemon/gssh/internal/vault"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
-	"golang.org/x/crypto/ssh/knownhosts"
 	"golang.org/x/term"
 )
 
@@ -20,314 +24,266 @@ type Client struct {
 	config *ssh.ClientConfig
 }
 
-// Connect 连接到服务器并执行命令（交互式，全部通过 expect 实现）
-func Connect(hostname string, user string, port int, authConfig AuthConfig) error {
-	fmt.Println("认证类型: ", authConfig.Type)
+// Connect 连接到服务器并打开交互式 shell，返回远程 shell 的退出码
+// jumps 为按顺序经过的跳板机，每一跳使用各自的认证配置
+func Connect(hostname string, user string, port int, authConfig AuthConfig, jumps ...Endpoint) (int, error) {
+	target := Endpoint{Hostname: hostname, User: user, Port: port, Auth: authConfig}
+	if len(jumps) > 0 {
+		hops := make([]string, 0, len(jumps))
+		for _, j := range jumps {
+			hops = append(hops, j.Address())
+		}
+		fmt.Printf("经由跳板机 %s 连接...\n", strings.Join(hops, " -> "))
+	}
+	client, err := dialChain(target, jumps, interactiveClientConfig)
+	if err != nil {
+		return -1, fmt.Errorf("建立 SSH 连接失败: %w", err)
+	}
+	defer client.Close()
+
+	return runShell(client)
+}
+
+// interactiveClientConfig 构建交互式登录使用的客户端配置
+// 按认证类型决定密钥、密码以及终端手动输入密码的组合
+func interactiveClientConfig(e Endpoint) (*ssh.ClientConfig, error) {
+	authConfig := e.Auth
+	fmt.Printf("[%s] 认证类型: %s\n", e.Hostname, authConfig.Type)
+
+	var authMethods []ssh.AuthMethod
+	var keyErrors []string
 	switch authConfig.Type {
 	case "key":
-		// 纯 key 模式：只加 -i，不自动填充密码；如果失败（Permission denied）直接退出。
+		// 纯 key 模式：只使用密钥，不自动填充密码；密钥失败后由用户手动输入密码。
 		fmt.Println("使用密钥文件（key 模式，失败后用户手动输入密码）...")
-		return connectWithKeyExpect(hostname, user, port, authConfig.IdentityFile)
+		authMethods, keyErrors = keyAuthMethods(authConfig.IdentityFile, authConfig.PassphraseCmd, true)
+		authMethods = append(authMethods, promptPasswordAuth(e.User, e.Hostname)...)
 	case "auto":
-		// auto 模式：加 -i，若密钥认证失败、出现密码提示则自动填充密码。
+		// auto 模式：先用密钥，密钥认证失败后自动用配置的密码登录。
 		fmt.Println("使用密钥 + 密码自动回退（auto 模式）...")
-		return connectWithAutoExpect(hostname, user, port, authConfig.IdentityFile, authConfig.Password)
+		authMethods, keyErrors = keyAuthMethods(authConfig.IdentityFile, authConfig.PassphraseCmd, true)
+		authMethods = append(authMethods, passwordAuth(e.User, e.Hostname, authConfig)...)
 	case "password":
-		// password 模式：不加 -i，只用密码登录。
+		// password 模式：不使用密钥，只用密码登录。
 		fmt.Println("使用密码登录（password 模式）...")
-		return connectWithPassword(hostname, user, port, authConfig.Password)
+		authMethods = passwordAuth(e.User, e.Hostname, authConfig)
 	default:
 		// 兜底逻辑：尽量不惊动老配置
 		if authConfig.IdentityFile != "" {
 			fmt.Println("未知认证类型，按 auto 处理（key + password）...")
-			return connectWithAutoExpect(hostname, user, port, authConfig.IdentityFile, authConfig.Password)
+			authMethods, keyErrors = keyAuthMethods(authConfig.IdentityFile, authConfig.PassphraseCmd, true)
+		} else {
+			fmt.Println("未知认证类型，按 password 处理（仅密码）...")
 		}
-		fmt.Println("未知认证类型，按 password 处理（仅密码）...")
-		return connectWithPassword(hostname, user, port, authConfig.Password)
+		authMethods = append(authMethods, passwordAuth(e.User, e.Hostname, authConfig)...)
 	}
-}
 
-// escapeExpectString 转义expect脚本中的特殊字符
-func escapeExpectString(s string) string {
-	// 转义expect中的特殊字符: [, ], {, }, $, ", \, 等
-	// 注意：使用 send -- 后，大部分特殊字符不需要转义，但为了安全起见还是转义
-	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, "[", "\\[")
-	s = strings.ReplaceAll(s, "]", "\\]")
-	s = strings.ReplaceAll(s, "{", "\\{")
-	s = strings.ReplaceAll(s, "}", "\\}")
-	s = strings.ReplaceAll(s, "$", "\\$")
-	s = strings.ReplaceAll(s, "\"", "\\\"")
-	s = strings.ReplaceAll(s, "'", "\\'")
-	return s
-}
-
-// connectWithPassword 使用密码连接（通过expect）
-func connectWithPassword(hostname string, user string, port int, password string) error {
-	// password 模式：不加 -i，只使用密码自动登录
-	// 构建SSH命令
-	sshArgs := []string{
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-p", fmt.Sprintf("%d", port),
-		fmt.Sprintf("%s@%s", user, hostname),
+	for _, keyErr := range keyErrors {
+		fmt.Fprintf(os.Stderr, "警告: %s\n", keyErr)
 	}
 
-	// 转义密码中的特殊字符（使用专门的函数）
-	escapedPassword := escapeExpectString(password)
-	// 转义SSH命令参数中的特殊字符
-	escapedSSHArgs := escapeExpectString(strings.Join(sshArgs, " "))
-
-	// 使用 expect 自动输入密码
-	expectScript := fmt.Sprintf(`
-set timeout 30
-
-set logged_in 0
-
-spawn ssh %s
-
-expect {
-		-re "(?i)(yes/no)" {
-			sleep 0.1
-			send "yes\r"
-			exp_continue
-		}
-		-re "(?i)(permission denied|failed password)" {
-			puts stderr "Authentication failed"
-			exit 1
-		}
-		-re "(?i)(password|Password):" {
-			sleep 0.1
-			send -- "%s\r"
-			exp_continue
-		}
-		-re {.*[\$#] } {
-			# 匹配可能的 shell 提示符
-			set logged_in 1
-		}
-		eof {
-			if {$logged_in == 0} {
-				# 连接在未登录成功前就结束，视为失败
-				exit 1
-			}
-		}
-}
+	hostKeyCallback, err := HostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
 
-if {$logged_in == 1} {
-	# 只有确认已经进入 shell 后才进入交互
-	interact
+	return &ssh.ClientConfig{
+		User:            e.User,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	}, nil
 }
 
-exit
-`, escapedSSHArgs, escapedPassword)
-
-	cmd := exec.Command("expect", "-c", expectScript)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
+// keyAuthMethods 构建密钥相关的认证方法（ssh-agent + 密钥文件）
+// 密钥有密码保护时，配置了 passphraseCmd 则执行命令获取密码，否则提示用户输入（interactive 为 false 时不提示，跳过该密钥）
+// 返回的错误列表只用于诊断，不影响其他认证方式
+func keyAuthMethods(identityFile, passphraseCmd string, interactive bool) ([]ssh.AuthMethod, []string) {
+	var authMethods []ssh.AuthMethod
+	var keyErrors []string
 
-// connectWithKeyExpect 仅使用密钥（type=key），不自动填充密码
-func connectWithKeyExpect(hostname string, user string, port int, identityFile string) error {
-	// 展开密钥路径
-	keyPath := identityFile
-	if keyPath != "" && keyPath[0] == '~' {
-		homeDir, _ := os.UserHomeDir()
-		keyPath = filepath.Join(homeDir, keyPath[1:])
+	// 首先尝试使用 ssh-agent（如果可用）
+	if agentAuth := getAgentAuth(); agentAuth != nil {
+		authMethods = append(authMethods, agentAuth)
 	}
 
-	sshArgs := []string{
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
+	if identityFile == "" {
+		return authMethods, keyErrors
 	}
-	if keyPath != "" {
-		sshArgs = append(sshArgs, "-i", keyPath)
-	}
-	sshArgs = append(sshArgs,
-		"-p", fmt.Sprintf("%d", port),
-		fmt.Sprintf("%s@%s", user, hostname),
-	)
-
-	escapedSSHArgs := escapeExpectString(strings.Join(sshArgs, " "))
 
-	// key 模式：不判断是否有 password，不自动填充。
-	// 如果出现密码提示，直接把控制权交给用户；如果出现 Permission denied 等错误则直接退出。
-	expectScript := fmt.Sprintf(`
-set timeout 30
+	keyPath := expandHome(identityFile)
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		keyErrors = append(keyErrors, fmt.Sprintf("无法读取密钥文件 %s: %v", keyPath, err))
+		return authMethods, keyErrors
+	}
 
-spawn ssh %s
+	signer, err := ssh.ParsePrivateKey(key)
+	if err == nil {
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+		return authMethods, keyErrors
+	}
 
-expect {
-	-re "(?i)(yes/no)" {
-		sleep 0.1
-		send "yes\r"
-		exp_continue
+	// 检查是否是密码保护的密钥
+	if !strings.Contains(err.Error(), "passphrase") {
+		keyErrors = append(keyErrors, fmt.Sprintf("无法解析密钥文件 %s: %v", keyPath, err))
+		return authMethods, keyErrors
 	}
-	-re "(?i)(permission denied|failed password)" {
-		puts stderr "Authentication failed"
-		exit 1
+
+	// 如果 ssh-agent 已经可用，就不需要输入 passphrase
+	// ssh-agent 中的密钥会优先使用
+	if len(authMethods) > 0 {
+		return authMethods, keyErrors
 	}
-	-re "(?i)(password|Password):" {
-		# 出现密码提示时，不自动输入密码，直接交给用户
-		interact
-		exit
+
+	// ssh-agent 不可用，执行 passphrase_cmd 或提示用户输入 passphrase
+	var passphrase string
+	switch {
+	case passphraseCmd != "" && interactive:
+		passphrase, err = vault.RunCommand("passphrase_cmd", passphraseCmd)
+	case passphraseCmd != "":
+		passphrase, err = vault.RunCommandQuiet("passphrase_cmd", passphraseCmd)
+	case interactive:
+		passphrase, err = promptPassphrase(keyPath)
+	default:
+		err = fmt.Errorf("密钥 %s 有密码保护，非交互连接无法输入密码（请使用 ssh-agent 或配置 passphrase_cmd）", keyPath)
 	}
-	-re {.*[\$#] } {
-		# 已经进入 shell，交互
-		interact
-		exit
+	if err != nil {
+		keyErrors = append(keyErrors, fmt.Sprintf("无法获取密钥密码: %v", err))
+		return authMethods, keyErrors
 	}
-	eof {
-		# 连接结束且未成功登录
-		exit 1
+
+	// 使用 passphrase 解析密钥
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+	if err != nil {
+		keyErrors = append(keyErrors, fmt.Sprintf("无法解析密钥文件（密码错误）: %v", err))
+		return authMethods, keyErrors
 	}
+	authMethods = append(authMethods, ssh.PublicKeys(signer))
+	return authMethods, keyErrors
 }
 
-exit
-`, escapedSSHArgs)
+// passwordAuth 使用配置的密码进行认证，未配置密码时回退到提示用户输入
+// 保险库加密的密码和 password_cmd 在服务器要求密码认证时才获取
+func passwordAuth(user, hostname string, authConfig AuthConfig) []ssh.AuthMethod {
+	if !authConfig.HasPassword() {
+		return promptPasswordAuth(user, hostname)
+	}
 
-	cmd := exec.Command("expect", "-c", expectScript)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	getPassword := passwordSource(authConfig, true)
+
+	// 同时支持 password 与 keyboard-interactive 两种服务端密码认证方式
+	return []ssh.AuthMethod{
+		ssh.PasswordCallback(getPassword),
+		ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range questions {
+				pw, err := getPassword()
+				if err != nil {
+					return nil, err
+				}
+				answers[i] = pw
+			}
+			return answers, nil
+		}),
+	}
 }
 
-// connectWithAutoExpect 使用密钥，失败时自动用密码填充（type=auto）
-func connectWithAutoExpect(hostname string, user string, port int, identityFile, password string) error {
-	// 展开密钥路径
-	keyPath := identityFile
-	if keyPath != "" && keyPath[0] == '~' {
-		homeDir, _ := os.UserHomeDir()
-		keyPath = filepath.Join(homeDir, keyPath[1:])
+// promptPasswordAuth 在终端提示用户手动输入密码（最多重试 3 次）
+func promptPasswordAuth(user, hostname string) []ssh.AuthMethod {
+	return []ssh.AuthMethod{
+		ssh.RetryableAuthMethod(ssh.PasswordCallback(func() (string, error) {
+			return util.ReadPassword(fmt.Sprintf("%s@%s's password: ", user, hostname))
+		}), 3),
+		ssh.RetryableAuthMethod(ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			if instruction != "" {
+				fmt.Println(instruction)
+			}
+			answers := make([]string, len(questions))
+			for i, question := range questions {
+				answer, err := util.ReadPassword(question)
+				if err != nil {
+					return nil, err
+				}
+				answers[i] = answer
+			}
+			return answers, nil
+		}), 3),
 	}
+}
 
-	sshArgs := []string{
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-	}
-	if keyPath != "" {
-		sshArgs = append(sshArgs, "-i", keyPath)
+// expandHome 展开路径开头的 ~
+func expandHome(path string) string {
+	if path != "" && path[0] == '~' {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[1:])
 	}
-	sshArgs = append(sshArgs,
-		"-p", fmt.Sprintf("%d", port),
-		fmt.Sprintf("%s@%s", user, hostname),
-	)
-
-	escapedSSHArgs := escapeExpectString(strings.Join(sshArgs, " "))
-	escapedPassword := escapeExpectString(password)
-
-	expectScript := fmt.Sprintf(`
-set timeout 30
+	return path
+}
 
-set logged_in 0
+// AuthConfig 认证配置（从config包导入的类型）
+type AuthConfig struct {
+	Type          string
+	Password      string
+	PasswordCmd   string // 获取密码的外部命令，未配置 Password 时使用
+	IdentityFile  string
+	PassphraseCmd string // 获取密钥密码的外部命令
+}
 
-spawn ssh %s
+// HasPassword 判断是否配置了密码（直接配置或通过外部命令获取）
+func (a AuthConfig) HasPassword() bool {
+	return a.Password != "" || a.PasswordCmd != ""
+}
 
-expect {
-	-re "(?i)(yes/no)" {
-		sleep 0.1
-		send "yes\r"
-		exp_continue
-	}
-	-re "(?i)(permission denied|failed password)" {
-		puts stderr "Authentication failed"
-		exit 1
-	}
-	-re "(?i)(password|Password):" {
-		# 密钥失败后，自动用密码登录
-		sleep 0.1
-		send -- "%s\r"
-		exp_continue
-	}
-	-re {.*[\$#] } {
-		set logged_in 1
-	}
-	eof {
-		if {$logged_in == 0} {
-			exit 1
-		}
+// NewSSHClient 创建SSH客户端（用于程序化操作，非交互式登录）
+// jumps 为按顺序经过的跳板机，每一跳使用各自的认证配置
+func NewSSHClient(hostname string, user string, port int, authConfig AuthConfig, jumps ...Endpoint) (*ssh.Client, error) {
+	target := Endpoint{Hostname: hostname, User: user, Port: port, Auth: authConfig}
+	client, err := dialChain(target, jumps, clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %w", err)
 	}
-}
 
-if {$logged_in == 1} {
-	interact
+	return client, nil
 }
 
-exit
-`, escapedSSHArgs, escapedPassword)
+// NewSyncSSHClient 创建连接 SSH 同步服务器的客户端，主机密钥未知或不一致时提示运行 SyncTrustHint
+// interactive 为 false 时遇到未知主机直接失败，不在终端询问（用于后台自动同步）
+func NewSyncSSHClient(hostname string, user string, port int, authConfig AuthConfig, interactive bool) (*ssh.Client, error) {
+	target := Endpoint{Hostname: hostname, User: user, Port: port, Auth: authConfig}
+	client, err := dialChain(target, nil, func(e Endpoint) (*ssh.ClientConfig, error) {
+		return newClientConfig(e, interactive, SyncTrustHint)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %w", err)
+	}
 
-	cmd := exec.Command("expect", "-c", expectScript)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return client, nil
 }
 
-// AuthConfig 认证配置（从config包导入的类型）
-type AuthConfig struct {
-	Type         string
-	Password     string
-	IdentityFile string
+// batchClientConfig 返回构建后台任务客户端配置的函数
+// 完全不在终端交互：未知主机直接失败（提示运行 trustHint），不提示输入密钥密码或主密码，外部密码命令不读取终端输入
+func batchClientConfig(trustHint string) func(Endpoint) (*ssh.ClientConfig, error) {
+	return func(e Endpoint) (*ssh.ClientConfig, error) {
+		return newClientConfig(e, false, trustHint)
+	}
 }
 
-// NewSSHClient 创建SSH客户端（用于程序化操作，非交互式登录）
-func NewSSHClient(hostname string, user string, port int, authConfig AuthConfig) (*ssh.Client, error) {
-	var authMethods []ssh.AuthMethod
-	var keyErrors []string
-
-	// 首先尝试使用 ssh-agent（如果可用）
-	if agentAuth := getAgentAuth(); agentAuth != nil {
-		authMethods = append(authMethods, agentAuth)
-	}
+// clientConfig 构建程序化操作使用的客户端配置（不提示用户输入密码）
+func clientConfig(e Endpoint) (*ssh.ClientConfig, error) {
+	return newClientConfig(e, true, ServerTrustHint)
+}
 
-	// 尝试密钥认证
-	if authConfig.IdentityFile != "" {
-		keyPath := authConfig.IdentityFile
-		if keyPath[0] == '~' {
-			homeDir, _ := os.UserHomeDir()
-			keyPath = filepath.Join(homeDir, keyPath[1:])
-		}
+// newClientConfig 构建程序化操作使用的客户端配置，interactive 为 false 时用于后台任务
+func newClientConfig(e Endpoint, interactive bool, trustHint string) (*ssh.ClientConfig, error) {
+	authConfig := e.Auth
 
-		key, err := os.ReadFile(keyPath)
-		if err != nil {
-			keyErrors = append(keyErrors, fmt.Sprintf("无法读取密钥文件 %s: %v", keyPath, err))
-		} else {
-			signer, err := ssh.ParsePrivateKey(key)
-			if err != nil {
-				// 检查是否是密码保护的密钥
-				if strings.Contains(err.Error(), "passphrase") {
-					// 如果 ssh-agent 已经可用，就不需要输入 passphrase
-					// ssh-agent 中的密钥会优先使用
-					if len(authMethods) == 0 {
-						// ssh-agent 不可用，提示用户输入 passphrase
-						passphrase, err := promptPassphrase(keyPath)
-						if err != nil {
-							keyErrors = append(keyErrors, fmt.Sprintf("无法获取密钥密码: %v", err))
-						} else {
-							// 使用 passphrase 解析密钥
-							signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
-							if err != nil {
-								keyErrors = append(keyErrors, fmt.Sprintf("无法解析密钥文件（密码错误）: %v", err))
-							} else {
-								authMethods = append(authMethods, ssh.PublicKeys(signer))
-							}
-						}
-					}
-					// 如果 ssh-agent 可用，跳过文件解析，直接使用 agent 中的密钥
-				} else {
-					keyErrors = append(keyErrors, fmt.Sprintf("无法解析密钥文件 %s: %v", keyPath, err))
-				}
-			} else {
-				authMethods = append(authMethods, ssh.PublicKeys(signer))
-			}
-		}
-	}
+	// 尝试 ssh-agent 与密钥认证
+	authMethods, keyErrors := keyAuthMethods(authConfig.IdentityFile, authConfig.PassphraseCmd, interactive)
 
-	// 添加密码认证
-	if authConfig.Password != "" {
-		authMethods = append(authMethods, ssh.Password(authConfig.Password))
+	// 添加密码认证（保险库加密的密码和 password_cmd 在需要时才获取）
+	if authConfig.HasPassword() {
+		authMethods = append(authMethods, ssh.PasswordCallback(passwordSource(authConfig, interactive)))
 	}
 
 	if len(authMethods) == 0 {
@@ -339,32 +295,26 @@ func NewSSHClient(hostname string, user string, port int, authConfig AuthConfig)
 			errMsg.WriteString(keyErrors[0])
 			errMsg.WriteString("\n")
 		}
-		if authConfig.IdentityFile == "" && authConfig.Password == "" {
+		if authConfig.IdentityFile == "" && !authConfig.HasPassword() {
 			errMsg.WriteString("\n请配置 SSH 密钥路径或密码。")
-		} else if authConfig.Password == "" {
+		} else if !authConfig.HasPassword() {
 			errMsg.WriteString("\n密钥认证失败且未配置密码，请检查密钥文件或配置密码。")
 		}
 		return nil, fmt.Errorf("%s", errMsg.String())
 	}
 
-	// 设置known_hosts
-	homeDir, _ := os.UserHomeDir()
-	knownHostsPath := filepath.Join(homeDir, ".ssh", "known_hosts")
-	hostKeyCallback, _ := knownhosts.New(knownHostsPath)
-
-	config := &ssh.ClientConfig{
-		User:            user,
-		Auth:            authMethods,
-		HostKeyCallback: hostKeyCallback,
-	}
-
-	addr := fmt.Sprintf("%s:%d", hostname, port)
-	client, err := ssh.Dial("tcp", addr, config)
+	// 使用 gssh 维护的 known_hosts 校验主机密钥（交互时首次连接询问）
+	callback, err := hostKeyCallback(interactive, trustHint)
 	if err != nil {
-		return nil, fmt.Errorf("连接失败: %w", err)
+		return nil, err
 	}
 
-	return client, nil
+	return &ssh.ClientConfig{
+		User:            e.User,
+		Auth:            authMethods,
+		HostKeyCallback: callback,
+		Timeout:         30 * time.Second,
+	}, nil
 }
 
 // ExecuteCommand 在远程服务器执行命令
@@ -383,6 +333,42 @@ func ExecuteCommand(client *ssh.Client, command string) (string, error) {
 	return string(output), nil
 }
 
+// RunCommand 在远程服务器执行命令，输出实时写入 stdout/stderr，返回远程退出码
+// ctx 取消或超时时会终止远程命令
+func RunCommand(ctx context.Context, client *ssh.Client, command string, stdout, stderr io.Writer) (int, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return -1, fmt.Errorf("创建会话失败: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	if err := session.Start(command); err != nil {
+		return -1, fmt.Errorf("启动命令失败: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		return -1, ctx.Err()
+	case err := <-done:
+		if err == nil {
+			return 0, nil
+		}
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitStatus(), nil
+		}
+		return -1, err
+	}
+}
+
 // getAgentAuth 尝试从 ssh-agent 获取认证方法
 func getAgentAuth() ssh.AuthMethod {
 	// 检查 SSH_AUTH_SOCK 环境变量
@@ -424,10 +410,3 @@ func promptPassphrase(keyPath string) (string, error) {
 
 	return string(passphrase), nil
 }
-
-// CopyFile 通过SSH复制文件（已弃用，使用scp命令代替）
-// 保留此方法以保持API兼容性，但实际实现已改用系统scp命令
-func CopyFile(client *ssh.Client, localPath string, remotePath string) error {
-	// 这个方法不再使用，sync包已改用系统scp命令
-	return fmt.Errorf("此方法已弃用，请使用系统scp命令")
-}
@@ -0,0 +1,276 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/util"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
+)
+
+// HostKeyEntry known_hosts 中的一条主机密钥记录
+type HostKeyEntry struct {
+	Hosts []string
+	Key   ssh.PublicKey
+}
+
+// errHostKeyFetched 获取主机密钥后用于中止握手的哨兵错误
+var errHostKeyFetched = errors.New("host key fetched")
+
+// 无法询问用户时，提示如何确认未知主机的密钥
+const (
+	ServerTrustHint = "gssh hostkey trust <server>"         // 配置中的服务器（包括跳板机）
+	SyncTrustHint   = "gssh hostkey trust --sync [profile]" // SSH 同步服务器
+)
+
+// KnownHostsPath 获取 gssh 自己维护的 known_hosts 路径（~/.gssh/known_hosts）
+// 文件不存在时会创建一个空文件
+func KnownHostsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户目录失败: %w", err)
+	}
+
+	dir := filepath.Join(homeDir, ".gssh")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建配置目录失败: %w", err)
+	}
+
+	path := filepath.Join(dir, "known_hosts")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return "", fmt.Errorf("创建 known_hosts 失败: %w", err)
+	}
+	f.Close()
+
+	return path, nil
+}
+
+// HostKeyCallback 返回首次信任（TOFU）的主机密钥校验回调
+// - 已记录且一致：直接通过
+// - 首次连接：显示指纹并询问用户是否信任，信任后写入 known_hosts
+// - 与记录不一致：直接失败并给出中间人攻击警告
+func HostKeyCallback() (ssh.HostKeyCallback, error) {
+	return hostKeyCallback(true, ServerTrustHint)
+}
+
+// hostKeyCallback 返回主机密钥校验回调，interactive 为 false 时未知主机直接失败（用于后台任务）
+// trustHint 为无法询问时错误信息中提示的确认命令
+func hostKeyCallback(interactive bool, trustHint string) (ssh.HostKeyCallback, error) {
+	path, err := KnownHostsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		// 每次都重新读取，保证同一进程内刚信任的主机（例如跳板机）立即生效
+		checker, err := knownhosts.New(path)
+		if err != nil {
+			return fmt.Errorf("读取 known_hosts 失败: %w", err)
+		}
+
+		err = checker(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) == 0 {
+				if !interactive {
+					return unknownHostError(hostname, key, trustHint)
+				}
+				return confirmUnknownHost(hostname, key, trustHint)
+			}
+			return hostKeyMismatchError(hostname, keyErr.Want, key, trustHint)
+		}
+
+		var revokedErr *knownhosts.RevokedError
+		if errors.As(err, &revokedErr) {
+			return fmt.Errorf("主机 %s 的密钥已被吊销，拒绝连接", hostname)
+		}
+
+		return err
+	}, nil
+}
+
+// confirmUnknownHost 首次连接时显示指纹并询问用户是否信任
+func confirmUnknownHost(hostname string, key ssh.PublicKey, trustHint string) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return unknownHostError(hostname, key, trustHint)
+	}
+
+	fmt.Printf("首次连接主机 %s\n", hostname)
+	fmt.Printf("%s 密钥指纹: %s\n", key.Type(), fingerprint)
+	fmt.Print("是否信任该主机并继续连接? (yes/no): ")
+	var answer string
+	fmt.Scanln(&answer)
+	if !util.IsYes(answer) {
+		return fmt.Errorf("用户拒绝信任主机 %s", hostname)
+	}
+
+	if err := AddHostKey(hostname, key); err != nil {
+		return err
+	}
+	fmt.Printf("已将 %s 添加到 known_hosts\n", hostname)
+	return nil
+}
+
+// unknownHostError 无法询问用户时，未知主机的错误信息
+func unknownHostError(hostname string, key ssh.PublicKey, trustHint string) error {
+	return fmt.Errorf("未知主机 %s（%s 指纹 %s），请先运行 '%s' 确认主机密钥",
+		hostname, key.Type(), ssh.FingerprintSHA256(key), trustHint)
+}
+
+// hostKeyMismatchError 构建主机密钥不一致时的错误信息，trustHint 为确认新密钥的命令
+func hostKeyMismatchError(hostname string, want []knownhosts.KnownKey, key ssh.PublicKey, trustHint string) error {
+	var b strings.Builder
+	b.WriteString("\n@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@\n")
+	b.WriteString("@    警告：远程主机密钥已改变！                            @\n")
+	b.WriteString("@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@\n")
+	b.WriteString("可能有人正在进行中间人攻击（MITM）！\n")
+	b.WriteString("也可能是主机密钥刚刚被更换。\n")
+	fmt.Fprintf(&b, "主机: %s\n", hostname)
+	fmt.Fprintf(&b, "当前 %s 指纹: %s\n", key.Type(), ssh.FingerprintSHA256(key))
+	for _, k := range want {
+		fmt.Fprintf(&b, "已记录 %s 指纹: %s (%s:%d)\n", k.Key.Type(), ssh.FingerprintSHA256(k.Key), k.Filename, k.Line)
+	}
+	fmt.Fprintf(&b, "如果确认主机密钥确实已更换，请运行 '%s' 确认新的主机密钥后重新连接", trustHint)
+	return fmt.Errorf("%s", b.String())
+}
+
+// AddHostKey 将主机密钥追加到 known_hosts
+// address 为 host:port 形式
+func AddHostKey(address string, key ssh.PublicKey) error {
+	path, err := KnownHostsPath()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("打开 known_hosts 失败: %w", err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(address)}, key)
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("写入 known_hosts 失败: %w", err)
+	}
+	return nil
+}
+
+// ListHostKeys 列出 known_hosts 中的所有主机密钥
+func ListHostKeys() ([]HostKeyEntry, error) {
+	path, err := KnownHostsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 known_hosts 失败: %w", err)
+	}
+
+	var entries []HostKeyEntry
+	for len(data) > 0 {
+		_, hosts, key, _, rest, err := ssh.ParseKnownHosts(data)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("解析 known_hosts 失败: %w", err)
+		}
+		entries = append(entries, HostKeyEntry{Hosts: hosts, Key: key})
+		data = rest
+	}
+
+	return entries, nil
+}
+
+// ForgetHostKey 从 known_hosts 中删除指定主机的所有密钥，返回删除的条数
+// address 为 host:port 形式
+func ForgetHostKey(address string) (int, error) {
+	path, err := KnownHostsPath()
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("读取 known_hosts 失败: %w", err)
+	}
+
+	target := knownhosts.Normalize(address)
+	var kept bytes.Buffer
+	removed := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		_, hosts, _, _, _, err := ssh.ParseKnownHosts([]byte(line))
+		if err == nil && slices.Contains(hosts, target) {
+			removed++
+			continue
+		}
+		kept.WriteString(line)
+		kept.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("读取 known_hosts 失败: %w", err)
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := util.WriteFileAtomic(path, kept.Bytes(), 0600); err != nil {
+		return 0, fmt.Errorf("写入 known_hosts 失败: %w", err)
+	}
+	return removed, nil
+}
+
+// FetchHostKey 连接到主机并获取其主机密钥（只完成密钥交换，不进行认证）
+// address 为 host:port 形式；jumps 不为空时经由跳板机链连接（跳板机需要正常认证）
+func FetchHostKey(address string, jumps ...Endpoint) (ssh.PublicKey, error) {
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: "gssh",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyFetched
+		},
+		Timeout: 30 * time.Second,
+	}
+
+	var conn *ssh.Client
+	var err error
+	if len(jumps) == 0 {
+		conn, err = ssh.Dial("tcp", address, config)
+	} else {
+		last := len(jumps) - 1
+		via, verr := dialChain(jumps[last], jumps[:last], interactiveClientConfig)
+		if verr != nil {
+			return nil, fmt.Errorf("连接跳板机 %s 失败: %w", jumps[last].Address(), verr)
+		}
+		defer via.Close()
+		conn, err = dialVia(via, address, config)
+	}
+	if conn != nil {
+		conn.Close()
+	}
+	if hostKey == nil {
+		return nil, fmt.Errorf("获取主机密钥失败: %w", err)
+	}
+	return hostKey, nil
+}
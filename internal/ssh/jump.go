@@ -0,0 +1,92 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Endpoint 连接目标（跳板机链中的一跳或最终服务器）
+type Endpoint struct {
+	Hostname string
+	User     string
+	Port     int
+	Auth     AuthConfig
+}
+
+// Address 返回 host:port 形式的地址，未配置端口时使用 22
+func (e Endpoint) Address() string {
+	port := e.Port
+	if port <= 0 {
+		port = 22
+	}
+	return net.JoinHostPort(e.Hostname, strconv.Itoa(port))
+}
+
+// dialChain 依次经过跳板机连接到目标服务器
+// 返回目标服务器的客户端，关闭它时会一并关闭所有跳板机连接
+func dialChain(target Endpoint, jumps []Endpoint, newConfig func(Endpoint) (*ssh.ClientConfig, error)) (*ssh.Client, error) {
+	hops := append(append([]Endpoint{}, jumps...), target)
+	clients := make([]*ssh.Client, 0, len(hops))
+	closeAll := func() {
+		for i := len(clients) - 1; i >= 0; i-- {
+			clients[i].Close()
+		}
+	}
+
+	for i, hop := range hops {
+		isJump := i < len(hops)-1
+		config, err := newConfig(hop)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+
+		var client *ssh.Client
+		if i == 0 {
+			client, err = ssh.Dial("tcp", hop.Address(), config)
+		} else {
+			client, err = dialVia(clients[i-1], hop.Address(), config)
+		}
+		if err != nil {
+			closeAll()
+			if isJump {
+				return nil, fmt.Errorf("连接跳板机 %s 失败: %w", hop.Address(), err)
+			}
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+
+	client := clients[len(clients)-1]
+	if len(clients) > 1 {
+		// 目标连接断开后关闭所有跳板机连接
+		jumpClients := clients[:len(clients)-1]
+		go func() {
+			client.Wait()
+			for i := len(jumpClients) - 1; i >= 0; i-- {
+				jumpClients[i].Close()
+			}
+		}()
+	}
+
+	return client, nil
+}
+
+// dialVia 通过已建立的 SSH 连接转发到下一跳并完成 SSH 握手
+func dialVia(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(c, chans, reqs), nil
+}
@@ -0,0 +1,83 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// remoteNotFoundStatus 远程文件不存在时读取命令的退出码
+const remoteNotFoundStatus = 44
+
+// ReadFile 读取远程文件内容，文件不存在时返回的错误可以用 errors.Is(err, os.ErrNotExist) 判断
+// 路径开头的 ~/ 由远程 shell 展开，其余部分会被安全引用
+func ReadFile(client *ssh.Client, remotePath string) ([]byte, error) {
+	p := ShellQuote(remotePath)
+	command := fmt.Sprintf("if [ -e %s ]; then cat -- %s; else exit %d; fi", p, p, remoteNotFoundStatus)
+
+	var stdout, stderr bytes.Buffer
+	if err := runWithInput(client, command, nil, &stdout, &stderr); err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitStatus() == remoteNotFoundStatus {
+			return nil, fmt.Errorf("远程文件 %s: %w", remotePath, os.ErrNotExist)
+		}
+		return nil, remoteCommandError(err, &stderr)
+	}
+	return stdout.Bytes(), nil
+}
+
+// WriteFileAtomic 原子写入远程文件：先写入同目录下的临时文件，完成后再 rename 覆盖目标文件
+// 读取方只会看到旧文件或完整的新文件；文件权限为 0600，父目录不存在时自动创建
+func WriteFileAtomic(client *ssh.Client, remotePath string, data []byte) error {
+	dir, base := path.Dir(remotePath), path.Base(remotePath)
+
+	// mktemp 模板的 XXXXXX 必须在引号外
+	template := ShellQuote(dir+"/."+base+".gssh-") + "XXXXXX"
+	command := strings.Join([]string{
+		"set -e",
+		"umask 077",
+		"mkdir -p -- " + ShellQuote(dir),
+		"tmp=$(mktemp " + template + ")",
+		`trap 'rm -f -- "$tmp"' EXIT`,
+		`cat > "$tmp"`,
+		`mv -f -- "$tmp" ` + ShellQuote(remotePath),
+		"trap - EXIT",
+	}, "; ")
+
+	var stderr bytes.Buffer
+	if err := runWithInput(client, command, bytes.NewReader(data), nil, &stderr); err != nil {
+		return remoteCommandError(err, &stderr)
+	}
+	return nil
+}
+
+// runWithInput 在远程执行命令，stdin 写入命令的标准输入
+func runWithInput(client *ssh.Client, command string, stdin *bytes.Reader, stdout, stderr *bytes.Buffer) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("创建会话失败: %w", err)
+	}
+	defer session.Close()
+
+	if stdin != nil {
+		session.Stdin = stdin
+	}
+	if stdout != nil {
+		session.Stdout = stdout
+	}
+	session.Stderr = stderr
+	return session.Run(command)
+}
+
+// remoteCommandError 将远程命令失败转换为包含远程错误输出的错误
+func remoteCommandError(err error, stderr *bytes.Buffer) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package ssh
+
+import (
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// watchWindowSize Windows 没有 SIGWINCH，定时轮询终端尺寸并同步到远程会话
+// 返回的函数用于停止轮询
+func watchWindowSize(fd int, session *ssh.Session) func() {
+	done := make(chan struct{})
+
+	go func() {
+		lastWidth, lastHeight, _ := term.GetSize(fd)
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				width, height, err := term.GetSize(fd)
+				if err != nil || (width == lastWidth && height == lastHeight) {
+					continue
+				}
+				lastWidth, lastHeight = width, height
+				session.WindowChange(height, width)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,371 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ProgressFunc 传输进度回调，done 为当前文件已传输字节数，total 为文件总大小
+type ProgressFunc func(name string, done, total int64)
+
+// Upload 通过 scp 协议上传本地文件（或目录，需 recursive）到远程路径，保留权限与时间戳
+func Upload(client *ssh.Client, localPath, remotePath string, recursive bool, progress ProgressFunc) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("读取本地文件失败: %w", err)
+	}
+	if info.IsDir() && !recursive {
+		return fmt.Errorf("%s 是目录，请使用 -r 递归复制", localPath)
+	}
+
+	flags := "-t -p"
+	if recursive {
+		flags += " -r"
+	}
+	return runSCP(client, fmt.Sprintf("scp %s -- %s", flags, ShellQuote(remotePath)), func(r *bufio.Reader, w io.Writer) error {
+		if err := readAck(r); err != nil {
+			return err
+		}
+		return sendEntry(r, w, localPath, info, progress)
+	})
+}
+
+// Download 通过 scp 协议下载远程文件（或目录，需 recursive）到本地路径，保留权限与时间戳
+func Download(client *ssh.Client, remotePath, localPath string, recursive bool, progress ProgressFunc) error {
+	flags := "-f -p"
+	if recursive {
+		flags += " -r"
+	}
+	return runSCP(client, fmt.Sprintf("scp %s -- %s", flags, ShellQuote(remotePath)), func(r *bufio.Reader, w io.Writer) error {
+		return receive(r, w, localPath, progress)
+	})
+}
+
+// CopyFile 通过SSH上传单个文件到远程路径
+func CopyFile(client *ssh.Client, localPath string, remotePath string) error {
+	return Upload(client, localPath, remotePath, false, nil)
+}
+
+// ShellQuote 将路径转义为远程 shell 的单个参数，保留开头的 ~/ 以便远程展开
+func ShellQuote(p string) string {
+	prefix := ""
+	if p == "~" {
+		return p
+	}
+	if strings.HasPrefix(p, "~/") {
+		prefix = "~/"
+		p = p[2:]
+	}
+	return prefix + "'" + strings.ReplaceAll(p, "'", `'\''`) + "'"
+}
+
+// runSCP 在远程启动 scp 并运行协议处理函数
+func runSCP(client *ssh.Client, command string, handle func(r *bufio.Reader, w io.Writer) error) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("创建会话失败: %w", err)
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("创建会话失败: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("创建会话失败: %w", err)
+	}
+	var stderr strings.Builder
+	session.Stderr = &stderr
+
+	if err := session.Start(command); err != nil {
+		return fmt.Errorf("启动远程 scp 失败: %w", err)
+	}
+
+	handleErr := handle(bufio.NewReader(stdout), stdin)
+	stdin.Close()
+	waitErr := session.Wait()
+
+	if handleErr != nil {
+		return handleErr
+	}
+	if waitErr != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("远程 scp 失败: %s", msg)
+		}
+		return fmt.Errorf("远程 scp 失败: %w", waitErr)
+	}
+	return nil
+}
+
+// sendEntry 发送一个文件或目录（source 端）
+func sendEntry(r *bufio.Reader, w io.Writer, localPath string, info os.FileInfo, progress ProgressFunc) error {
+	// 先发送时间戳（scp 不传访问时间，这里与修改时间保持一致）
+	mtime := info.ModTime().Unix()
+	if _, err := fmt.Fprintf(w, "T%d 0 %d 0\n", mtime, mtime); err != nil {
+		return err
+	}
+	if err := readAck(r); err != nil {
+		return err
+	}
+
+	name := filepath.Base(localPath)
+	mode := info.Mode().Perm()
+
+	if info.IsDir() {
+		if _, err := fmt.Fprintf(w, "D%04o 0 %s\n", mode, name); err != nil {
+			return err
+		}
+		if err := readAck(r); err != nil {
+			return err
+		}
+
+		entries, err := os.ReadDir(localPath)
+		if err != nil {
+			return fmt.Errorf("读取目录失败: %w", err)
+		}
+		for _, e := range entries {
+			childPath := filepath.Join(localPath, e.Name())
+			childInfo, err := os.Stat(childPath)
+			if err != nil {
+				return fmt.Errorf("读取本地文件失败: %w", err)
+			}
+			if !childInfo.IsDir() && !childInfo.Mode().IsRegular() {
+				continue
+			}
+			if err := sendEntry(r, w, childPath, childInfo, progress); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprint(w, "E\n"); err != nil {
+			return err
+		}
+		return readAck(r)
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("打开本地文件失败: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(w, "C%04o %d %s\n", mode, info.Size(), name); err != nil {
+		return err
+	}
+	if err := readAck(r); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, &progressReader{r: f, name: localPath, total: info.Size(), progress: progress}); err != nil {
+		return fmt.Errorf("传输 %s 失败: %w", localPath, err)
+	}
+	if _, err := w.Write([]byte{0}); err != nil {
+		return err
+	}
+	return readAck(r)
+}
+
+// receive 接收文件或目录（sink 端）
+func receive(r *bufio.Reader, w io.Writer, localPath string, progress ProgressFunc) error {
+	// 通知远程开始发送
+	if _, err := w.Write([]byte{0}); err != nil {
+		return err
+	}
+
+	// 目标是已存在的目录时，文件放到目录内
+	targetIsDir := false
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		targetIsDir = true
+	}
+
+	var dirs []string
+	var dirTimes []time.Time
+	var mtime time.Time
+	hasTime := false
+	started := false
+
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			if !started {
+				return fmt.Errorf("远程没有返回任何文件")
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取 scp 响应失败: %w", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return fmt.Errorf("无效的 scp 响应")
+		}
+
+		switch line[0] {
+		case 0x01, 0x02:
+			return fmt.Errorf("远程 scp 错误: %s", strings.TrimSpace(line[1:]))
+		case 'T':
+			fields := strings.Fields(line[1:])
+			if len(fields) != 4 {
+				return fmt.Errorf("无效的时间戳: %s", line)
+			}
+			sec, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("无效的时间戳: %s", line)
+			}
+			mtime = time.Unix(sec, 0)
+			hasTime = true
+		case 'D':
+			mode, _, name, err := parseSCPHeader(line)
+			if err != nil {
+				return err
+			}
+			dir := entryPath(localPath, dirs, name, targetIsDir || len(dirs) > 0)
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				return fmt.Errorf("创建目录失败: %w", err)
+			}
+			if err := os.Chmod(dir, mode); err != nil {
+				return fmt.Errorf("设置目录权限失败: %w", err)
+			}
+			dirs = append(dirs, dir)
+			if hasTime {
+				dirTimes = append(dirTimes, mtime)
+			} else {
+				dirTimes = append(dirTimes, time.Time{})
+			}
+			hasTime = false
+			started = true
+		case 'E':
+			if len(dirs) == 0 {
+				return fmt.Errorf("无效的 scp 响应: 目录结束标记不匹配")
+			}
+			dir, t := dirs[len(dirs)-1], dirTimes[len(dirTimes)-1]
+			dirs, dirTimes = dirs[:len(dirs)-1], dirTimes[:len(dirTimes)-1]
+			if !t.IsZero() {
+				os.Chtimes(dir, t, t)
+			}
+		case 'C':
+			mode, size, name, err := parseSCPHeader(line)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write([]byte{0}); err != nil {
+				return err
+			}
+			file := entryPath(localPath, dirs, name, targetIsDir || len(dirs) > 0)
+			if err := receiveFile(r, file, mode, size, progress); err != nil {
+				return err
+			}
+			if err := readAck(r); err != nil {
+				return err
+			}
+			if hasTime {
+				if err := os.Chtimes(file, mtime, mtime); err != nil {
+					return fmt.Errorf("设置文件时间失败: %w", err)
+				}
+			}
+			hasTime = false
+			started = true
+		default:
+			return fmt.Errorf("无效的 scp 响应: %q", line)
+		}
+
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+}
+
+// receiveFile 接收单个文件内容
+func receiveFile(r *bufio.Reader, file string, mode os.FileMode, size int64, progress ProgressFunc) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("创建本地文件失败: %w", err)
+	}
+	defer f.Close()
+
+	src := &progressReader{r: io.LimitReader(r, size), name: file, total: size, progress: progress}
+	if _, err := io.Copy(f, src); err != nil {
+		return fmt.Errorf("传输 %s 失败: %w", file, err)
+	}
+	if src.done != size {
+		return fmt.Errorf("传输 %s 失败: 数据不完整", file)
+	}
+	if err := f.Chmod(mode); err != nil {
+		return fmt.Errorf("设置文件权限失败: %w", err)
+	}
+	return nil
+}
+
+// entryPath 计算接收条目在本地的路径
+// inside 为 true 时条目放在当前目录（或目标目录）内，否则直接使用目标路径
+func entryPath(localPath string, dirs []string, name string, inside bool) string {
+	if len(dirs) > 0 {
+		return filepath.Join(dirs[len(dirs)-1], name)
+	}
+	if inside {
+		return filepath.Join(localPath, name)
+	}
+	return localPath
+}
+
+// parseSCPHeader 解析 C/D 消息，格式为 "C0644 1234 name"
+func parseSCPHeader(line string) (os.FileMode, int64, string, error) {
+	parts := strings.SplitN(line[1:], " ", 3)
+	if len(parts) != 3 {
+		return 0, 0, "", fmt.Errorf("无效的 scp 响应: %q", line)
+	}
+	mode, err := strconv.ParseUint(parts[0], 8, 32)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("无效的文件权限: %q", line)
+	}
+	size, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("无效的文件大小: %q", line)
+	}
+	name := parts[2]
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") || path.Base(name) != name {
+		return 0, 0, "", fmt.Errorf("远程返回了不安全的文件名: %q", name)
+	}
+	return os.FileMode(mode).Perm(), size, name, nil
+}
+
+// readAck 读取 scp 应答：0 成功，1 警告，2 错误
+func readAck(r *bufio.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("读取 scp 应答失败: %w", err)
+	}
+	if b == 0 {
+		return nil
+	}
+	msg, _ := r.ReadString('\n')
+	return fmt.Errorf("远程 scp 错误: %s", strings.TrimSpace(msg))
+}
+
+// progressReader 在读取时回调传输进度
+type progressReader struct {
+	r        io.Reader
+	name     string
+	total    int64
+	done     int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.done += int64(n)
+	if p.progress != nil && (n > 0 || p.total == 0) {
+		p.progress(p.name, p.done, p.total)
+	}
+	return n, err
+}
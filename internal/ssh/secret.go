@@ -0,0 +1,25 @@
+package ssh
+
+import (
+	"sync"
+
+	"github.com/fijdemon/gssh/internal/vault"
+)
+
+// passwordSource 返回按需获取密码的函数，同一次连接内只获取一次
+// 优先使用配置的密码（保险库密文会被解密），否则执行 password_cmd；interactive 为 false 时不在终端交互
+func passwordSource(authConfig AuthConfig, interactive bool) func() (string, error) {
+	var once sync.Once
+	var password string
+	var err error
+	return func() (string, error) {
+		once.Do(func() {
+			if interactive {
+				password, err = vault.Resolve(authConfig.Password, authConfig.PasswordCmd, "password_cmd")
+			} else {
+				password, err = vault.ResolveQuiet(authConfig.Password, authConfig.PasswordCmd, "password_cmd")
+			}
+		})
+		return password, err
+	}
+}
@@ -0,0 +1,79 @@
+package ssh
+
+import (
+	"fmt"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// ServerEndpoint 将服务器配置转换为连接目标
+func ServerEndpoint(s config.Server) Endpoint {
+	return Endpoint{
+		Hostname: s.Hostname,
+		User:     s.User,
+		Port:     s.GetPort(),
+		Auth: AuthConfig{
+			Type:          s.Auth.Type,
+			Password:      s.Auth.Password,
+			PasswordCmd:   s.Auth.PasswordCmd,
+			IdentityFile:  s.Auth.IdentityFile,
+			PassphraseCmd: s.Auth.PassphraseCmd,
+		},
+	}
+}
+
+// ServerJumps 解析服务器的跳板机链并转换为连接目标
+func ServerJumps(cfg *config.Config, s config.Server) ([]Endpoint, error) {
+	if len(s.Jump) == 0 {
+		return nil, nil
+	}
+
+	chain, err := cfg.ResolveJumpChain(s.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	jumps := make([]Endpoint, 0, len(chain))
+	for _, hop := range chain {
+		jumps = append(jumps, ServerEndpoint(hop))
+	}
+	return jumps, nil
+}
+
+// ConnectServer 按服务器配置（含跳板机链）打开交互式 shell，返回远程 shell 的退出码
+func ConnectServer(cfg *config.Config, s config.Server) (int, error) {
+	jumps, err := ServerJumps(cfg, s)
+	if err != nil {
+		return -1, err
+	}
+
+	e := ServerEndpoint(s)
+	return Connect(e.Hostname, e.User, e.Port, e.Auth, jumps...)
+}
+
+// NewServerClient 按服务器配置（含跳板机链）创建 SSH 客户端
+func NewServerClient(cfg *config.Config, s config.Server) (*ssh.Client, error) {
+	jumps, err := ServerJumps(cfg, s)
+	if err != nil {
+		return nil, err
+	}
+
+	e := ServerEndpoint(s)
+	return NewSSHClient(e.Hostname, e.User, e.Port, e.Auth, jumps...)
+}
+
+// NewBatchServerClient 与 NewServerClient 相同，但完全不在终端交互（用于并行执行）
+// 未知主机、需要输入的密钥密码和锁定的保险库都作为该服务器的错误返回
+func NewBatchServerClient(cfg *config.Config, s config.Server) (*ssh.Client, error) {
+	jumps, err := ServerJumps(cfg, s)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dialChain(ServerEndpoint(s), jumps, batchClientConfig(ServerTrustHint))
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,71 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// runShell 在已建立的连接上打开交互式 shell，直到远程 shell 退出
+// 返回远程 shell 的退出码
+func runShell(client *ssh.Client) (int, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return -1, fmt.Errorf("创建会话失败: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = "xterm-256color"
+		}
+
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+		if err := session.RequestPty(termType, height, width, modes); err != nil {
+			return -1, fmt.Errorf("申请伪终端失败: %w", err)
+		}
+
+		// 本地终端切换到 raw 模式，按键原样转发给远程
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return -1, fmt.Errorf("设置终端 raw 模式失败: %w", err)
+		}
+		defer term.Restore(fd, oldState)
+
+		// 转发本地终端窗口大小变化
+		stop := watchWindowSize(fd, session)
+		defer stop()
+	}
+
+	if err := session.Shell(); err != nil {
+		return -1, fmt.Errorf("启动远程 shell 失败: %w", err)
+	}
+
+	if err := session.Wait(); err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitStatus(), nil
+		}
+		return -1, fmt.Errorf("会话异常结束: %w", err)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,178 @@
+package sshconfig
+
+import (
+	"fmt"
+	"net"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/config"
+)
+
+// Candidate 一台待导入的服务器
+type Candidate struct {
+	Server   config.Server
+	Source   string   // 来源位置（file:line），由 ProxyJump 自动生成的跳板机为空
+	Conflict bool     // 名称与现有服务器冲突，不会导入
+	Requires []string // 依赖的其他待导入跳板机（导入本服务器时一并导入）
+}
+
+// Plan 将解析出的主机转换为待导入的服务器，并检查与现有配置的名称冲突
+// ProxyJump 中引用的别名会转换为 jump；无法对应到别名或现有服务器的跳板机（如 user@host:port）会自动生成一条服务器记录
+func Plan(cfg *config.Config, hosts []Host, group string) []Candidate {
+	existing := make(map[string]bool, len(cfg.Servers))
+	for _, s := range cfg.Servers {
+		existing[s.Name] = true
+	}
+	aliases := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		aliases[h.Alias] = true
+	}
+
+	var candidates []Candidate
+	index := make(map[string]int)
+	add := func(c Candidate) {
+		c.Conflict = existing[c.Server.Name]
+		index[c.Server.Name] = len(candidates)
+		candidates = append(candidates, c)
+	}
+
+	for _, h := range hosts {
+		s := config.Server{
+			Name:        h.Alias,
+			Hostname:    h.HostName,
+			User:        defaultUser(h.User),
+			Port:        h.Port,
+			Description: "从 ssh_config 导入",
+			Group:       group,
+			Tags:        []string{},
+			Auth: config.AuthConfig{
+				Type:         "auto",
+				IdentityFile: h.IdentityFile,
+			},
+		}
+
+		var requires []string
+		for _, hop := range strings.Split(h.ProxyJump, ",") {
+			hop = strings.TrimSpace(hop)
+			if hop == "" {
+				continue
+			}
+			name, hopServer := parseJumpSpec(hop)
+			if !aliases[name] && !existing[name] {
+				// 不是已知别名，生成一条跳板机记录
+				hopServer.Group = group
+				if _, ok := index[name]; !ok {
+					add(Candidate{Server: hopServer})
+				}
+			}
+			if aliases[name] || !existing[name] {
+				requires = append(requires, name)
+			}
+			s.Jump = append(s.Jump, name)
+		}
+
+		add(Candidate{Server: s, Source: h.Source(), Requires: requires})
+	}
+	return candidates
+}
+
+// Apply 导入选中的服务器（以及它们依赖的跳板机），返回实际导入的服务器名称
+// 冲突的服务器会被跳过；selected 为 nil 时导入全部不冲突的服务器
+func Apply(cfg *config.Config, candidates []Candidate, selected map[string]bool) ([]string, error) {
+	byName := make(map[string]Candidate, len(candidates))
+	for _, c := range candidates {
+		byName[c.Server.Name] = c
+	}
+
+	var added []string
+	done := make(map[string]bool)
+	var importOne func(name string, path []string) error
+	importOne = func(name string, path []string) error {
+		if done[name] {
+			return nil
+		}
+		c, ok := byName[name]
+		if !ok || c.Conflict {
+			// 引用的是现有服务器
+			return nil
+		}
+		for _, p := range path {
+			if p == name {
+				return fmt.Errorf("跳板机循环引用: %s -> %s", strings.Join(path, " -> "), name)
+			}
+		}
+		// 先导入跳板机，AddServer 会检查跳板机是否存在
+		for _, dep := range c.Requires {
+			if err := importOne(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		if err := cfg.AddServer(c.Server); err != nil {
+			return fmt.Errorf("导入 %s 失败: %w", name, err)
+		}
+		done[name] = true
+		added = append(added, name)
+		return nil
+	}
+
+	for _, c := range candidates {
+		if c.Conflict || (selected != nil && !selected[c.Server.Name]) {
+			continue
+		}
+		if err := importOne(c.Server.Name, nil); err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+// parseJumpSpec 解析 ProxyJump 中的一跳（[user@]host[:port]），返回引用名称和对应的服务器记录
+func parseJumpSpec(spec string) (string, config.Server) {
+	userName := ""
+	hostPort := spec
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		userName = spec[:i]
+		hostPort = spec[i+1:]
+	}
+
+	host, port := hostPort, 0
+	if h, p, err := net.SplitHostPort(hostPort); err == nil {
+		host = h
+		port, _ = strconv.Atoi(p)
+	}
+
+	// 只写了别名时直接引用该别名
+	if userName == "" && port == 0 {
+		return host, config.Server{
+			Name:        host,
+			Hostname:    host,
+			User:        defaultUser(""),
+			Description: "ssh_config ProxyJump 跳板机",
+			Tags:        []string{},
+			Auth:        config.AuthConfig{Type: "auto"},
+		}
+	}
+
+	return spec, config.Server{
+		Name:        spec,
+		Hostname:    host,
+		User:        defaultUser(userName),
+		Port:        port,
+		Description: "ssh_config ProxyJump 跳板机",
+		Tags:        []string{},
+		Auth:        config.AuthConfig{Type: "auto"},
+	}
+}
+
+// defaultUser 未指定用户时与 ssh 一样使用当前本地用户
+func defaultUser(name string) string {
+	if name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "root"
+}
@@ -0,0 +1,314 @@
+package sshconfig
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxIncludeDepth Include 最大嵌套层数，与 OpenSSH 保持一致
+const maxIncludeDepth = 16
+
+// Host ssh_config 中一个具体主机别名的生效配置
+type Host struct {
+	Alias        string // Host 行中的别名
+	HostName     string // 实际连接的地址，未配置时为别名本身
+	User         string
+	Port         int // 未配置时为 0
+	IdentityFile string
+	ProxyJump    string
+	File         string // 别名首次出现的文件
+	Line         int    // 别名首次出现的行号
+}
+
+// Source 返回别名定义位置（file:line）
+func (h Host) Source() string {
+	return fmt.Sprintf("%s:%d", h.File, h.Line)
+}
+
+// block 一个 Host/Match 段
+type block struct {
+	patterns []string
+	match    bool // Match 段，暂不支持，始终视为不匹配
+	options  []option
+}
+
+// option 段内的一条配置
+type option struct {
+	key   string // 小写关键字
+	value string
+}
+
+// parser 解析状态
+type parser struct {
+	baseDir  string
+	blocks   []*block
+	aliases  []Host
+	seen     map[string]bool
+	warnings []string
+}
+
+// DefaultPath 返回默认的 ~/.ssh/config 路径
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户目录失败: %w", err)
+	}
+	return filepath.Join(home, ".ssh", "config"), nil
+}
+
+// Parse 解析 ssh_config 文件（包括 Include 的文件），返回所有具体主机别名的生效配置
+// 含通配符（* ? !）的 Host 模式不会作为主机返回，但其中的配置会按 OpenSSH 规则作用于匹配的别名
+func Parse(file string) ([]Host, []string, error) {
+	p := &parser{
+		baseDir: filepath.Dir(file),
+		seen:    make(map[string]bool),
+	}
+	// 第一个 Host 之前的配置对所有主机生效
+	p.blocks = append(p.blocks, &block{patterns: []string{"*"}})
+
+	if err := p.parseFile(file, 0); err != nil {
+		return nil, p.warnings, err
+	}
+
+	hosts := make([]Host, 0, len(p.aliases))
+	for _, h := range p.aliases {
+		hosts = append(hosts, p.resolve(h))
+	}
+	return hosts, p.warnings, nil
+}
+
+// parseFile 逐行解析单个文件
+func (p *parser) parseFile(file string, depth int) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", file, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		key, args, err := splitLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", file, lineNo, err)
+		}
+		if key == "" {
+			continue
+		}
+
+		switch key {
+		case "host":
+			b := &block{patterns: args}
+			p.blocks = append(p.blocks, b)
+			for _, alias := range args {
+				if isPattern(alias) || p.seen[alias] {
+					continue
+				}
+				p.seen[alias] = true
+				p.aliases = append(p.aliases, Host{Alias: alias, File: file, Line: lineNo})
+			}
+		case "match":
+			p.blocks = append(p.blocks, &block{match: true})
+			p.warnings = append(p.warnings, fmt.Sprintf("%s:%d: 不支持 Match 段，已忽略", file, lineNo))
+		case "include":
+			if depth+1 > maxIncludeDepth {
+				return fmt.Errorf("%s:%d: Include 嵌套过深", file, lineNo)
+			}
+			for _, pattern := range args {
+				if err := p.include(pattern, depth+1); err != nil {
+					return fmt.Errorf("%s:%d: %w", file, lineNo, err)
+				}
+			}
+		default:
+			if len(args) == 0 {
+				continue
+			}
+			current := p.blocks[len(p.blocks)-1]
+			current.options = append(current.options, option{key: key, value: args[0]})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", file, err)
+	}
+	return nil
+}
+
+// include 展开 Include 路径（支持 ~ 和通配符，相对路径相对于主配置文件所在目录）
+func (p *parser) include(pattern string, depth int) error {
+	pattern = expandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(p.baseDir, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("无效的 Include 路径 %s: %w", pattern, err)
+	}
+	for _, m := range matches {
+		if info, err := os.Stat(m); err != nil || info.IsDir() {
+			continue
+		}
+		if err := p.parseFile(m, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve 按 OpenSSH 规则（先出现的值优先）计算别名的生效配置
+func (p *parser) resolve(h Host) Host {
+	values := make(map[string]string)
+	for _, b := range p.blocks {
+		if !b.matches(h.Alias) {
+			continue
+		}
+		for _, opt := range b.options {
+			if _, ok := values[opt.key]; !ok {
+				values[opt.key] = opt.value
+			}
+		}
+	}
+
+	h.User = values["user"]
+	h.HostName = h.Alias
+	if v, ok := values["hostname"]; ok {
+		h.HostName = expandTokens(v, h.Alias, h.Alias, h.User)
+	}
+	if port, err := strconv.Atoi(values["port"]); err == nil {
+		h.Port = port
+	}
+	if v, ok := values["identityfile"]; ok && !strings.EqualFold(v, "none") {
+		h.IdentityFile = expandTokens(v, h.Alias, h.HostName, h.User)
+	}
+	if v, ok := values["proxyjump"]; ok && !strings.EqualFold(v, "none") {
+		h.ProxyJump = v
+	}
+	return h
+}
+
+// matches 判断别名是否匹配该段的模式（任一正向模式匹配且没有否定模式匹配）
+func (b *block) matches(alias string) bool {
+	if b.match {
+		return false
+	}
+	matched := false
+	for _, pattern := range b.patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if ok, _ := path.Match(pattern, alias); ok {
+			if negate {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// isPattern 判断 Host 模式是否包含通配符
+func isPattern(s string) bool {
+	return strings.ContainsAny(s, "*?!")
+}
+
+// splitLine 拆分配置行为小写关键字和参数，支持 "key value"、"key=value" 和双引号
+func splitLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	key := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+
+	var args []string
+	var current strings.Builder
+	inQuote, hasArg := false, false
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			hasArg = true
+		case (r == ' ' || r == '\t') && !inQuote:
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		case r == '#' && !inQuote && !hasArg:
+			// 行尾注释
+			return key, args, nil
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if inQuote {
+		return "", nil, fmt.Errorf("引号未闭合")
+	}
+	if hasArg {
+		args = append(args, current.String())
+	}
+	return key, args, nil
+}
+
+// expandTokens 展开常用的 ssh_config 占位符（%% %h %n %r %d %u），路径开头的 ~ 原样保留
+func expandTokens(s, alias, hostname, remoteUser string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	home, _ := os.UserHomeDir()
+	localUser := ""
+	if u, err := user.Current(); err == nil {
+		localUser = u.Username
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case '%':
+			b.WriteByte('%')
+		case 'h':
+			b.WriteString(hostname)
+		case 'n':
+			b.WriteString(alias)
+		case 'r':
+			b.WriteString(remoteUser)
+		case 'd':
+			b.WriteString(home)
+		case 'u':
+			b.WriteString(localUser)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
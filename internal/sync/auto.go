@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/vault"
+)
+
+// AutoSyncTimeout 自动同步访问同步后端的超时时间
+const AutoSyncTimeout = 10 * time.Second
+
+// AutoEnabled 判断是否有同步目标开启了自动同步（enabled 与 auto_sync）
+func AutoEnabled(cfg *config.Config) bool {
+	if cfg.Sync.Enabled && cfg.Sync.AutoSync {
+		return true
+	}
+	for _, p := range cfg.SyncProfiles {
+		if p.Enabled && p.AutoSync {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoPull 后台自动拉取开启了 auto_sync 的同步目标：不输出、不在终端询问
+// 超时、合并冲突或需要输入主密码时直接返回错误，不修改本地配置
+func AutoPull() error {
+	opts := Options{Quiet: true, Timeout: AutoSyncTimeout}
+	return eachTarget(opts, func(name, _ string) error {
+		_, err := pull(name, opts)
+		return err
+	})
+}
+
+// AutoPush 后台自动推送，规则与 AutoPull 相同
+func AutoPush() error {
+	opts := Options{Quiet: true, Timeout: AutoSyncTimeout}
+	return eachTarget(opts, func(name, _ string) error {
+		_, err := push(name, opts)
+		return err
+	})
+}
+
+// checkQuiet 后台同步前检查是否需要交互：需要解密保险库中的密码但保险库未解锁时直接失败
+func checkQuiet(cfg *config.Config, name string, opts Options) error {
+	if !opts.Quiet || vault.Unlocked() {
+		return nil
+	}
+	sc, err := cfg.SyncTarget(name)
+	if err != nil {
+		return err
+	}
+
+	needed := sc.Encryption.Mode == vault.PayloadRecipients
+	for _, secret := range cfg.Secrets() {
+		if vault.IsSealed(*secret) {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		// 同步基线中可能还有已从本地删除的服务器的密码
+		basePath, err := config.GetSyncBasePath(name)
+		if err != nil {
+			return err
+		}
+		base, err := config.LoadSnapshot(basePath)
+		if err != nil {
+			return err
+		}
+		for _, s := range base {
+			if vault.IsSealed(s.Auth.Password) {
+				needed = true
+				break
+			}
+		}
+	}
+
+	if needed {
+		return fmt.Errorf("保险库已锁定，请先运行 'gssh vault unlock'")
+	}
+	return nil
+}
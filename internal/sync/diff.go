@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/fijdemon/gssh/internal/config"
+)
+
+// 服务器变更类型
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// FieldChange 一个字段的变更，密码只显示是否设置
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ServerChange 一台服务器的变更
+type ServerChange struct {
+	Name   string        `json:"name"`
+	Kind   string        `json:"kind"`
+	Target string        `json:"target,omitempty"` // 新增 / 删除的服务器的 user@hostname:port
+	Fields []FieldChange `json:"fields,omitempty"` // 修改的字段
+}
+
+// Plan pull / push 的预览：写入前后服务器列表的差异
+type Plan struct {
+	Profile   string         `json:"profile"`   // 同步目标（default 或 sync profile 名称）
+	Direction string         `json:"direction"` // pull 修改本地配置，push 修改远程配置
+	Changes   []ServerChange `json:"changes"`
+	Conflicts []Conflict     `json:"conflicts"`
+}
+
+// Diff 比较写入前后的服务器列表，返回新增、删除和修改的服务器
+// 结果按 after 的顺序排列，删除的服务器排在最后
+func Diff(before, after []config.Server) []ServerChange {
+	beforeMap := indexServers(before)
+	afterMap := indexServers(after)
+
+	changes := []ServerChange{}
+	for _, s := range after {
+		old, ok := beforeMap[s.Name]
+		if !ok {
+			changes = append(changes, ServerChange{Name: s.Name, Kind: ChangeAdded, Target: serverTarget(s)})
+			continue
+		}
+		var fields []FieldChange
+		for _, f := range mergeFields {
+			ov, nv := f.get(&old), f.get(&s)
+			if valuesEqual(ov, nv) {
+				continue
+			}
+			fields = append(fields, FieldChange{Field: f.name, Old: formatValue(ov, f.secret), New: formatValue(nv, f.secret)})
+		}
+		if len(fields) > 0 {
+			changes = append(changes, ServerChange{Name: s.Name, Kind: ChangeChanged, Fields: fields})
+		}
+	}
+	for _, s := range before {
+		if _, ok := afterMap[s.Name]; !ok {
+			changes = append(changes, ServerChange{Name: s.Name, Kind: ChangeRemoved, Target: serverTarget(s)})
+		}
+	}
+	return changes
+}
+
+// removedServers 返回变更中被删除的服务器名称
+func removedServers(changes []ServerChange) []string {
+	var names []string
+	for _, c := range changes {
+		if c.Kind == ChangeRemoved {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// serverTarget 返回服务器的 user@hostname:port
+func serverTarget(s config.Server) string {
+	return fmt.Sprintf("%s@%s:%d", s.User, s.Hostname, s.GetPort())
+}
@@ -0,0 +1,123 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/util"
+	"github.com/fijdemon/gssh/internal/vault"
+	"gopkg.in/yaml.v3"
+)
+
+// encryptedServers 加密内容中保存的服务器列表
+type encryptedServers struct {
+	Servers []config.Server `yaml:"servers"`
+}
+
+// encryptionEnabled 判断是否启用了同步内容加密
+func encryptionEnabled(enc *config.SyncEncryption) bool {
+	return enc.Mode != ""
+}
+
+// sealServers 启用加密时将推送内容中的服务器列表替换为密文
+// quiet 为 true 时不在终端询问口令
+func sealServers(enc *config.SyncEncryption, pushCfg *config.Config, quiet bool) error {
+	if !encryptionEnabled(enc) {
+		return nil
+	}
+
+	plain, err := yaml.Marshal(encryptedServers{Servers: pushCfg.Servers})
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+
+	var payload *vault.Payload
+	switch enc.Mode {
+	case vault.PayloadPassphrase:
+		passphrase, err := syncPassphrase(enc, quiet)
+		if err != nil {
+			return err
+		}
+		payload, err = vault.SealWithPassphrase(plain, passphrase)
+		if err != nil {
+			return err
+		}
+	case vault.PayloadRecipients:
+		payload, err = vault.SealForRecipients(plain, enc.Recipients)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("不支持的同步加密方式: %s（可选 passphrase、recipients）", enc.Mode)
+	}
+
+	pushCfg.Servers = []config.Server{}
+	pushCfg.Encrypted = payload
+	return nil
+}
+
+// errPlainRemote 启用了加密，但远程配置仍是明文
+var errPlainRemote = errors.New("已启用同步加密，但远程配置是明文，可能已被篡改；如果刚启用加密，请先运行 'gssh push' 加密远程配置")
+
+// openServers 返回远程配置中的服务器列表，加密内容在本地解密
+// 解密或校验失败时返回错误，调用方不会修改本地配置
+func openServers(enc *config.SyncEncryption, remote *config.Config, quiet bool) ([]config.Server, error) {
+	if remote.Encrypted == nil {
+		// 空的明文列表同样拒绝，否则篡改为 servers: [] 就能让合并删除本地的服务器
+		if encryptionEnabled(enc) {
+			return nil, errPlainRemote
+		}
+		return remote.Servers, nil
+	}
+
+	var (
+		plain []byte
+		err   error
+	)
+	switch enc.Mode {
+	case "":
+		return nil, fmt.Errorf("远程配置已加密，请在 sync.encryption 中配置解密方式（%s）", remote.Encrypted.Mode)
+	case vault.PayloadPassphrase:
+		passphrase, perr := syncPassphrase(enc, quiet)
+		if perr != nil {
+			return nil, perr
+		}
+		plain, err = remote.Encrypted.OpenWithPassphrase(passphrase)
+	case vault.PayloadRecipients:
+		plain, err = remote.Encrypted.OpenAsRecipient(enc.Recipients)
+	default:
+		return nil, fmt.Errorf("不支持的同步加密方式: %s（可选 passphrase、recipients）", enc.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded encryptedServers
+	if err := yaml.Unmarshal(plain, &decoded); err != nil {
+		return nil, fmt.Errorf("解析解密后的配置失败: %w", err)
+	}
+	if decoded.Servers == nil {
+		decoded.Servers = []config.Server{}
+	}
+	return decoded.Servers, nil
+}
+
+// syncPassphrase 获取同步加密口令：配置值、passphrase_cmd，都没有时在终端提示输入（quiet 时直接报错）
+func syncPassphrase(enc *config.SyncEncryption, quiet bool) (string, error) {
+	resolve := vault.Resolve
+	if quiet {
+		resolve = vault.ResolveQuiet
+	}
+	passphrase, err := resolve(enc.Passphrase, enc.PassphraseCmd, "passphrase_cmd")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != "" {
+		return passphrase, nil
+	}
+	if quiet || !util.IsTerminal() {
+		return "", fmt.Errorf("同步加密口令未配置（在 sync.encryption 中设置 passphrase 或 passphrase_cmd）")
+	}
+	return util.ReadPassword("请输入同步加密口令: ")
+}
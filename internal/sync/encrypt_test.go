@@ -0,0 +1,42 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fijdemon/gssh/internal/config"
+)
+
+func TestOpenServersRejectsPlainRemote(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		servers []config.Server
+		wantErr bool
+	}{
+		{name: "未启用加密", servers: []config.Server{{Name: "web1"}}},
+		{name: "未启用加密的空列表", servers: []config.Server{}},
+		{name: "启用加密后的明文", mode: "passphrase", servers: []config.Server{{Name: "web1"}}, wantErr: true},
+		{name: "启用加密后的空明文列表", mode: "passphrase", servers: []config.Server{}, wantErr: true},
+		{name: "启用加密后没有 servers", mode: "recipients", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := &config.SyncEncryption{Mode: tt.mode, Passphrase: "secret"}
+			servers, err := openServers(enc, &config.Config{Servers: tt.servers}, true)
+			if tt.wantErr {
+				if !errors.Is(err, errPlainRemote) {
+					t.Fatalf("错误 = %v，期望 errPlainRemote", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("读取明文远程配置失败: %v", err)
+			}
+			if len(servers) != len(tt.servers) {
+				t.Errorf("服务器数量 = %d，期望 %d", len(servers), len(tt.servers))
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package sync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// GitSync Git仓库方式同步，通过系统 git 命令操作 ~/.gssh/ 下的工作副本
+// 工作副本只是远程分支的缓存：拉取时直接重置到远程分支，合并由 gssh 的三方合并完成，因此 git 本身不会产生冲突
+type GitSync struct {
+	config *config.SyncConfig
+	quiet  bool // 后台同步：禁止 ssh 询问主机密钥或口令
+}
+
+// NewGitSync 创建Git同步实例
+func NewGitSync(cfg *config.SyncConfig) *GitSync {
+	return &GitSync{config: cfg}
+}
+
+// Pull 获取远程分支并读取其中的配置文件
+func (s *GitSync) Pull() (*config.Config, error) {
+	path, err := s.path()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := s.prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.remoteBranchExists(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w（%s 的 %s 分支），请先运行 'gssh push'", ErrRemoteNotFound, s.config.GitRepo, s.branch())
+	}
+	if err := s.resetToRemote(dir); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, path))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w（仓库中没有 %s），请先运行 'gssh push'", ErrRemoteNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取远程配置失败: %w", err)
+	}
+
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析远程配置失败: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Push 将配置写入工作副本，提交并推送到远程分支
+// 推送失败时工作副本会恢复到远程分支的状态，不留下未推送的提交
+func (s *GitSync) Push(cfg *config.Config) (err error) {
+	path, err := s.path()
+	if err != nil {
+		return err
+	}
+	dir, err := s.prepare()
+	if err != nil {
+		return err
+	}
+
+	exists, err := s.remoteBranchExists(dir)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := s.resetToRemote(dir); err != nil {
+			return err
+		}
+	} else if err := s.orphanBranch(dir); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			s.restore(dir, exists)
+		}
+	}()
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	file := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+
+	if _, err := s.git(dir, "add", "--", path); err != nil {
+		return err
+	}
+	// 内容没有变化时不产生空提交
+	if _, err := s.git(dir, "diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+
+	if _, err := s.run(dir, s.identityEnv(dir), "commit", "--quiet", "-m", commitMessage(len(cfg.Servers))); err != nil {
+		return err
+	}
+	if _, err := s.git(dir, "push", "origin", "HEAD:refs/heads/"+s.branch()); err != nil {
+		return fmt.Errorf("%w\n如果远程分支刚刚被其他人更新，请重新运行 'gssh push'", err)
+	}
+	return nil
+}
+
+// prepare 确保工作副本存在并获取远程最新提交，返回工作副本目录
+func (s *GitSync) prepare() (string, error) {
+	if s.config.GitRepo == "" {
+		return "", fmt.Errorf("Git 仓库地址未配置（在 sync 配置中设置 git_repo）")
+	}
+
+	dir, err := s.workDir()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+			return "", fmt.Errorf("创建目录失败: %w", err)
+		}
+		if _, err := s.git("", "clone", "--quiet", "--no-checkout", s.config.GitRepo, dir); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		return dir, nil
+	}
+
+	if _, err := s.git(dir, "fetch", "--quiet", "--prune", "origin"); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// remoteBranchExists 判断远程分支是否存在（空仓库或新分支时不存在）
+func (s *GitSync) remoteBranchExists(dir string) (bool, error) {
+	out, err := s.git(dir, "branch", "--remotes", "--list", "origin/"+s.branch())
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// resetToRemote 将工作副本切换到远程分支的最新提交，丢弃所有本地改动
+func (s *GitSync) resetToRemote(dir string) error {
+	if _, err := s.git(dir, "checkout", "--quiet", "--force", "-B", s.branch(), "origin/"+s.branch()); err != nil {
+		return err
+	}
+	if _, err := s.git(dir, "clean", "--quiet", "-fd"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// orphanBranch 远程分支不存在时创建一个没有历史的空分支
+func (s *GitSync) orphanBranch(dir string) error {
+	if _, err := s.git(dir, "checkout", "--quiet", "--force", "--orphan", s.branch()); err != nil {
+		return err
+	}
+	if _, err := s.git(dir, "rm", "-r", "--cached", "--quiet", "--ignore-unmatch", "."); err != nil {
+		return err
+	}
+	_, err := s.git(dir, "clean", "--quiet", "-fd")
+	return err
+}
+
+// restore 推送失败后恢复工作副本
+func (s *GitSync) restore(dir string, remoteExists bool) {
+	if remoteExists {
+		s.resetToRemote(dir)
+		return
+	}
+	// 远程分支还不存在时，删除本地提交，回到空分支
+	s.git(dir, "update-ref", "-d", "HEAD")
+	s.git(dir, "reset", "--quiet", "--hard")
+	s.git(dir, "clean", "--quiet", "-fd")
+}
+
+// identityEnv 用户没有配置 git 提交身份时，使用本机用户名作为提交者
+func (s *GitSync) identityEnv(dir string) []string {
+	if out, err := s.git(dir, "config", "user.email"); err == nil && strings.TrimSpace(out) != "" {
+		return nil
+	}
+	name, host := syncIdentity()
+	email := name + "@" + host
+	return []string{
+		"GIT_AUTHOR_NAME=" + name, "GIT_AUTHOR_EMAIL=" + email,
+		"GIT_COMMITTER_NAME=" + name, "GIT_COMMITTER_EMAIL=" + email,
+	}
+}
+
+// git 在工作副本中执行 git 命令
+func (s *GitSync) git(dir string, args ...string) (string, error) {
+	return s.run(dir, nil, args...)
+}
+
+// run 执行 git 命令，失败时返回包含 git 输出的错误
+// 禁止 git 在终端交互询问凭据，HTTPS 仓库请配置 credential helper，SSH 仓库请使用密钥或 ssh-agent
+func (s *GitSync) run(dir string, env []string, args ...string) (string, error) {
+	name := args[0]
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	if s.quiet && os.Getenv("GIT_SSH_COMMAND") == "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s 失败: %s", name, msg)
+	}
+	return stdout.String(), nil
+}
+
+// workDir 返回仓库对应的工作副本目录（~/.gssh/sync-git/<仓库地址摘要>）
+func (s *GitSync) workDir() (string, error) {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(s.config.GitRepo))
+	return filepath.Join(filepath.Dir(configPath), "sync-git", hex.EncodeToString(sum[:6])), nil
+}
+
+// branch 返回同步使用的分支
+func (s *GitSync) branch() string {
+	if s.config.GitBranch != "" {
+		return s.config.GitBranch
+	}
+	return "main"
+}
+
+// path 返回配置文件在仓库中的路径（相对于仓库根目录）
+// 绝对路径或包含 .. 的路径会写到工作副本之外，直接拒绝
+func (s *GitSync) path() (string, error) {
+	if s.config.GitPath == "" {
+		return "config.yaml", nil
+	}
+	p := filepath.ToSlash(filepath.Clean(s.config.GitPath))
+	if filepath.IsAbs(s.config.GitPath) || strings.HasPrefix(p, "/") || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("git_path 必须是仓库内的相对路径: %s", s.config.GitPath)
+	}
+	return p, nil
+}
+
+// commitMessage 生成推送时的提交信息
+func commitMessage(count int) string {
+	name, host := syncIdentity()
+	return fmt.Sprintf("gssh: 更新服务器列表（%d 台）\n\n推送自 %s@%s", count, name, host)
+}
+
+// syncIdentity 返回当前本地用户名和主机名
+func syncIdentity() (string, string) {
+	name := "gssh"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return name, host
+}
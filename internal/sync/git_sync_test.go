@@ -0,0 +1,44 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/fijdemon/gssh/internal/config"
+)
+
+func TestGitSyncPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		gitPath string
+		want    string
+		wantErr bool
+	}{
+		{name: "默认路径", want: "config.yaml"},
+		{name: "子目录", gitPath: "team/ops/gssh.yaml", want: "team/ops/gssh.yaml"},
+		{name: "清理多余的分隔符", gitPath: "./team//gssh.yaml", want: "team/gssh.yaml"},
+		{name: "目录内的 .. 清理后仍在仓库内", gitPath: "team/../gssh.yaml", want: "gssh.yaml"},
+		{name: "绝对路径", gitPath: "/etc/gssh.yaml", wantErr: true},
+		{name: "跳出仓库", gitPath: "../gssh.yaml", wantErr: true},
+		{name: "清理后跳出仓库", gitPath: "team/../../gssh.yaml", wantErr: true},
+		{name: "仓库根目录", gitPath: ".", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewGitSync(&config.SyncConfig{Type: "git", GitRepo: "git@example.com:ops/gssh.git", GitPath: tt.gitPath})
+			got, err := s.path()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("git_path %q 应被拒绝，实际为 %q", tt.gitPath, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("git_path %q 被拒绝: %v", tt.gitPath, err)
+			}
+			if got != tt.want {
+				t.Errorf("路径 = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
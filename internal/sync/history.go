@@ -0,0 +1,200 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+)
+
+// defaultHistoryLimit 同步后端默认保留的历史版本数量
+const defaultHistoryLimit = 10
+
+// historyIDFormat 历史版本 ID 的时间格式
+const historyIDFormat = "20060102-150405"
+
+// newPushConfig 创建推送到同步目标 sc 的配置：服务器列表（启用加密时为密文）和历史版本，不包含 sync 配置
+// 本次推送作为最新的历史版本记录推送者的用户名和主机名，超过 history_limit 的旧版本被丢弃
+func newPushConfig(cfg *config.Config, sc *config.SyncConfig, servers []config.Server, history []config.HistoryEntry, note string, quiet bool) (*config.Config, error) {
+	flat := flattenServers(servers)
+	pushCfg := &config.Config{
+		Version: cfg.Version,
+		Servers: flat,
+		// Sync 部分不推送，由各客户端自己维护
+	}
+	if err := sealServers(&sc.Encryption, pushCfg, quiet); err != nil {
+		return nil, fmt.Errorf("加密配置失败: %w", err)
+	}
+
+	name, host := syncIdentity()
+	now := time.Now()
+	entry := config.HistoryEntry{
+		ID:        historyID(now, history),
+		Time:      now.Format(time.RFC3339),
+		User:      name,
+		Host:      host,
+		Count:     len(flat),
+		Note:      note,
+		Servers:   pushCfg.Servers,
+		Encrypted: pushCfg.Encrypted,
+	}
+
+	kept := []config.HistoryEntry{entry}
+	limit := sc.HistoryLimit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	for _, h := range history {
+		if len(kept) >= limit {
+			break
+		}
+		// 启用加密后不再保留明文的旧版本，同步后端只保存密文
+		if encryptionEnabled(&sc.Encryption) && h.Encrypted == nil {
+			continue
+		}
+		kept = append(kept, h)
+	}
+	pushCfg.History = kept
+	return pushCfg, nil
+}
+
+// historyID 生成历史版本 ID（推送时间），同一秒内多次推送时追加序号
+func historyID(t time.Time, history []config.HistoryEntry) string {
+	base := t.Format(historyIDFormat)
+	id := base
+	for n := 2; findHistory(history, id) != nil; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	return id
+}
+
+// findHistory 按 ID 查找历史版本
+func findHistory(history []config.HistoryEntry, id string) *config.HistoryEntry {
+	for i := range history {
+		if history[i].ID == id {
+			return &history[i]
+		}
+	}
+	return nil
+}
+
+// History 返回同步目标 profile 的同步后端保存的历史版本（最新的在前），profile 为空时使用 sync 配置
+func History(profile string) ([]config.HistoryEntry, error) {
+	_, _, _, remoteCfg, err := fetchRemote(profile)
+	if err != nil {
+		return nil, err
+	}
+	return remoteCfg.History, nil
+}
+
+// ShowVersion 返回历史版本及其服务器列表（加密内容在本地解密）
+func ShowVersion(profile, id string) (*config.HistoryEntry, []config.Server, error) {
+	_, sc, _, remoteCfg, err := fetchRemote(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	entry := findHistory(remoteCfg.History, id)
+	if entry == nil {
+		return nil, nil, fmt.Errorf("历史版本不存在: %s（运行 'gssh sync log' 查看）", id)
+	}
+	servers, err := openHistory(sc, entry)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry, servers, nil
+}
+
+// Rollback 将同步目标 profile 的远程和本地服务器列表恢复到历史版本 id
+// 恢复的内容作为新版本推送，原有历史版本保留；本地只替换属于该目标的服务器；confirm 返回 false 时取消
+func Rollback(profile, id string, confirm func(entry *config.HistoryEntry, servers []config.Server) (bool, error)) error {
+	cfg, sc, sync, remoteCfg, err := fetchRemote(profile)
+	if err != nil {
+		return err
+	}
+	entry := findHistory(remoteCfg.History, id)
+	if entry == nil {
+		return fmt.Errorf("历史版本不存在: %s（运行 'gssh sync log' 查看）", id)
+	}
+	servers, err := openHistory(sc, entry)
+	if err != nil {
+		return err
+	}
+
+	ok, err := confirm(entry, servers)
+	if err != nil || !ok {
+		return err
+	}
+
+	name := targetName(profile)
+	// 先确认不会与本地其他目标的服务器重名再推送；推送在配置锁外进行，不会阻塞其他 gssh 进程
+	if _, err := replaceOwned(cfg, name, servers); err != nil {
+		return err
+	}
+	pushCfg, err := newPushConfig(cfg, sc, servers, remoteCfg.History, "回滚到 "+entry.ID, false)
+	if err != nil {
+		return err
+	}
+	if err := sync.Push(pushCfg); err != nil {
+		return fmt.Errorf("推送配置失败: %w", err)
+	}
+
+	// 本地同样恢复到该版本（保存时会先备份当前配置）
+	_, err = config.Update(func(latest *config.Config) error {
+		restored, err := replaceOwned(latest, name, servers)
+		if err != nil {
+			return err
+		}
+		latest.Servers = restored
+		return commitTarget(latest, name, sc)
+	})
+	if err != nil {
+		return err
+	}
+	return saveSyncBase(name, servers)
+}
+
+// fetchRemote 加载本地配置并获取同步目标 profile 的远程配置（不合并）
+// 返回的 sc 指向 cfg 中的同步配置，与返回的同步后端共享（例如 HTTP 的 ETag），之后用该后端推送可以检测并发修改
+func fetchRemote(profile string) (*config.Config, *config.SyncConfig, Sync, *config.Config, error) {
+	cfg, sc, err := loadTarget(targetName(profile))
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if err := validateSyncConfig(sc); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	sync, err := NewSync(sc)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	remoteCfg, err := sync.Pull()
+	if errors.Is(err, ErrRemoteNotFound) {
+		return nil, nil, nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("获取远程配置失败: %w", err)
+	}
+	return cfg, sc, sync, remoteCfg, nil
+}
+
+// targetName 返回同步目标名称，profile 为空时为 default
+func targetName(profile string) string {
+	if profile == "" {
+		return config.DefaultSyncTarget
+	}
+	return profile
+}
+
+// openHistory 返回历史版本中的服务器列表
+func openHistory(sc *config.SyncConfig, entry *config.HistoryEntry) ([]config.Server, error) {
+	servers, err := openServers(&sc.Encryption, &config.Config{Servers: entry.Servers, Encrypted: entry.Encrypted}, false)
+	if err != nil {
+		return nil, fmt.Errorf("读取历史版本 %s 失败: %w", entry.ID, err)
+	}
+	if servers == nil {
+		servers = []config.Server{}
+	}
+	return servers, nil
+}
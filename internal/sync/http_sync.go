@@ -0,0 +1,202 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/vault"
+	"gopkg.in/yaml.v3"
+)
+
+// httpTimeout HTTP 同步请求的超时时间
+const httpTimeout = 30 * time.Second
+
+// HTTPSync HTTP(S)方式同步：GET 拉取，PUT 推送
+// 推送时通过 If-Match 携带最后一次同步时的 ETag，服务端配置已被他人更新时返回 412，避免静默覆盖
+type HTTPSync struct {
+	config       *config.SyncConfig
+	client       *http.Client
+	quiet        bool   // 后台同步：token、password_cmd 不在终端交互
+	missing      bool   // 上一次拉取返回 404，推送时只允许创建
+	lastModified string // 上一次拉取的 Last-Modified，服务端不返回 ETag 时用于检测并发修改
+}
+
+// NewHTTPSync 创建HTTP同步实例
+func NewHTTPSync(cfg *config.SyncConfig) *HTTPSync {
+	return &HTTPSync{
+		config: cfg,
+		client: &http.Client{Timeout: httpTimeout},
+	}
+}
+
+// Pull 从远程地址拉取配置，并记录 ETag
+func (s *HTTPSync) Pull() (*config.Config, error) {
+	req, err := s.newRequest(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 %s 失败: %w", s.config.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		s.config.ETag = ""
+		s.missing, s.lastModified = true, ""
+		return nil, fmt.Errorf("%w（%s），请先运行 'gssh push'", ErrRemoteNotFound, s.config.URL)
+	}
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取远程配置失败: %w", err)
+	}
+
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析远程配置失败: %w", err)
+	}
+
+	s.config.ETag = resp.Header.Get("ETag")
+	s.missing, s.lastModified = false, resp.Header.Get("Last-Modified")
+	return &cfg, nil
+}
+
+// Push 推送配置到远程地址
+// gssh push 会先拉取合并，因此 412 只会出现在拉取与推送之间有其他人推送的情况
+// 已知远程版本时使用 If-Match；拉取时远程不存在则使用 If-None-Match: * 只允许创建；
+// 服务端不返回 ETag 时退回 If-Unmodified-Since，两者都没有时无法检测冲突，警告后直接覆盖
+func (s *HTTPSync) Push(cfg *config.Config) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+
+	req, err := s.newRequest(http.MethodPut, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/yaml")
+	switch {
+	case s.config.ETag != "":
+		req.Header.Set("If-Match", s.config.ETag)
+	case s.missing:
+		req.Header.Set("If-None-Match", "*")
+	case s.lastModified != "":
+		req.Header.Set("If-Unmodified-Since", s.lastModified)
+	case !s.quiet:
+		fmt.Fprintf(os.Stderr, "警告: %s 没有返回 ETag 或 Last-Modified，无法检测其他人的并发修改，将直接覆盖远程配置\n", s.config.URL)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 %s 失败: %w", s.config.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("远程配置刚刚被其他人更新，请重新运行 'gssh push'")
+	}
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	header := resp.Header
+	if header.Get("ETag") == "" {
+		// 服务端没有在 PUT 响应中返回 ETag 时，通过 HEAD 获取新版本
+		if header, err = s.head(); err != nil {
+			return err
+		}
+	}
+	s.config.ETag = header.Get("ETag")
+	s.missing, s.lastModified = false, header.Get("Last-Modified")
+	return nil
+}
+
+// head 获取远程配置当前的响应头（ETag、Last-Modified）
+func (s *HTTPSync) head() (http.Header, error) {
+	req, err := s.newRequest(http.MethodHead, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 %s 失败: %w", s.config.URL, err)
+	}
+	resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+	return resp.Header, nil
+}
+
+// newRequest 创建带认证信息的请求
+func (s *HTTPSync) newRequest(method string, body []byte) (*http.Request, error) {
+	if s.config.URL == "" {
+		return nil, fmt.Errorf("同步地址未配置（在 sync 配置中设置 url）")
+	}
+
+	req, err := http.NewRequest(method, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("无效的同步地址 %s: %w", s.config.URL, err)
+	}
+	if err := setAuth(req, s.config, s.quiet); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// setAuth 设置请求的认证信息（Token 优先，其次是 Basic 认证）
+// quiet 时保险库未解锁直接失败，password_cmd 不读取终端输入
+func setAuth(req *http.Request, cfg *config.SyncConfig, quiet bool) error {
+	req.Header.Set("User-Agent", "gssh")
+
+	reveal, resolve := vault.Reveal, vault.Resolve
+	if quiet {
+		reveal, resolve = vault.RevealQuiet, vault.ResolveQuiet
+	}
+
+	switch {
+	case cfg.Token != "":
+		token, err := reveal(cfg.Token)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case cfg.Username != "":
+		password, err := resolve(cfg.Password, cfg.PasswordCmd, "password_cmd")
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(cfg.Username, password)
+	}
+	return nil
+}
+
+// checkResponse 将非 2xx 响应转换为错误
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("认证失败（%s），请检查 token 或 username/password", resp.Status)
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if text := strings.TrimSpace(string(msg)); text != "" {
+		return fmt.Errorf("服务器返回 %s: %s", resp.Status, text)
+	}
+	return fmt.Errorf("服务器返回 %s", resp.Status)
+}
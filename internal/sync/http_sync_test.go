@@ -0,0 +1,114 @@
+package sync
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+)
+
+// httpServer 保存单个文件的 HTTP 服务端，不返回 ETag，只支持 Last-Modified 条件请求
+type httpServer struct {
+	*httptest.Server
+	mu       gosync.Mutex
+	data     []byte
+	modified time.Time
+	headers  []http.Header // 收到的 PUT 请求头
+}
+
+func newHTTPServer(t *testing.T) *httpServer {
+	t.Helper()
+	s := &httpServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if s.data == nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Last-Modified", s.modified.UTC().Format(http.TimeFormat))
+			w.Write(s.data)
+		case http.MethodPut:
+			s.headers = append(s.headers, r.Header.Clone())
+			if r.Header.Get("If-None-Match") == "*" && s.data != nil {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			if since := r.Header.Get("If-Unmodified-Since"); since != "" {
+				t, err := http.ParseTime(since)
+				if err != nil || s.modified.Truncate(time.Second).After(t) {
+					w.WriteHeader(http.StatusPreconditionFailed)
+					return
+				}
+			}
+			s.data, _ = io.ReadAll(r.Body)
+			// 精度为秒，保证每次写入后 Last-Modified 都会变化
+			s.modified = s.modified.Add(time.Second)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	s.modified = time.Now().Truncate(time.Second)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// lastPut 返回最后一次 PUT 的请求头
+func (s *httpServer) lastPut() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.headers[len(s.headers)-1]
+}
+
+func newTestHTTPSync(url string) *HTTPSync {
+	return NewHTTPSync(&config.SyncConfig{Type: "http", URL: url})
+}
+
+func TestHTTPPushWithoutETag(t *testing.T) {
+	srv := newHTTPServer(t)
+	a := newTestHTTPSync(srv.URL)
+
+	// 远程不存在时只允许创建
+	if _, err := a.Pull(); !errors.Is(err, ErrRemoteNotFound) {
+		t.Fatalf("远程不存在时 Pull 错误 = %v，期望 ErrRemoteNotFound", err)
+	}
+	if err := a.Push(testConfig("web1")); err != nil {
+		t.Fatalf("首次推送失败: %v", err)
+	}
+	if got := srv.lastPut().Get("If-None-Match"); got != "*" {
+		t.Errorf("远程不存在时 If-None-Match = %q，期望 *", got)
+	}
+
+	// 服务端不返回 ETag：之后的推送不能再用 If-None-Match，否则每次都会 412
+	if _, err := a.Pull(); err != nil {
+		t.Fatalf("拉取失败: %v", err)
+	}
+	if err := a.Push(testConfig("web1", "web2")); err != nil {
+		t.Fatalf("没有 ETag 时再次推送失败: %v", err)
+	}
+	h := srv.lastPut()
+	if h.Get("If-None-Match") != "" {
+		t.Errorf("远程已存在时不应发送 If-None-Match，实际 %q", h.Get("If-None-Match"))
+	}
+	if h.Get("If-Unmodified-Since") == "" {
+		t.Error("没有 ETag 时应使用 If-Unmodified-Since 检测并发修改")
+	}
+
+	// 拉取之后远程被其他人更新，推送必须失败
+	b := newTestHTTPSync(srv.URL)
+	if _, err := b.Pull(); err != nil {
+		t.Fatalf("B 拉取失败: %v", err)
+	}
+	if err := a.Push(testConfig("web1", "web2", "web3")); err != nil {
+		t.Fatalf("A 推送失败: %v", err)
+	}
+	if err := b.Push(testConfig("db1")); err == nil {
+		t.Fatal("远程已被更新时推送应失败")
+	}
+}
@@ -0,0 +1,286 @@
+package sync
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/util"
+)
+
+// Prefer 冲突时的优先方
+const (
+	PreferLocal  = "local"
+	PreferRemote = "remote"
+)
+
+// Conflict 一个合并冲突：双方都修改了同一字段（或一方删除、另一方修改了服务器）
+type Conflict struct {
+	Server string `json:"server"`
+	Field  string `json:"field,omitempty"` // 为空表示整台服务器的冲突（删除 / 修改）
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+}
+
+// String 返回冲突的描述
+func (c Conflict) String() string {
+	if c.Field == "" {
+		return fmt.Sprintf("[%s] 本地: %s，远程: %s", c.Server, c.Local, c.Remote)
+	}
+	return fmt.Sprintf("[%s] %s 本地: %s，远程: %s", c.Server, c.Field, c.Local, c.Remote)
+}
+
+// Resolver 决定冲突取哪一方，返回 true 表示使用本地的值
+type Resolver func(c Conflict) (useLocal bool, err error)
+
+// MergeResult 合并结果
+type MergeResult struct {
+	Servers   []config.Server
+	Conflicts []Conflict // 已解决的冲突
+}
+
+// serverField 参与合并的服务器字段
+type serverField struct {
+	name   string
+	secret bool // 展示时隐藏取值
+	get    func(s *config.Server) any
+	set    func(s *config.Server, v any)
+}
+
+// mergeFields 逐字段合并的服务器字段（last_used 取较新的值，created_at 保留已有值，不产生冲突）
+// template 只在本地有意义，推送和基线中都不包含，不参与合并；合并结果保留本地服务器引用的模板
+var mergeFields = []serverField{
+	{name: "hostname", get: func(s *config.Server) any { return s.Hostname }, set: func(s *config.Server, v any) { s.Hostname = v.(string) }},
+	{name: "user", get: func(s *config.Server) any { return s.User }, set: func(s *config.Server, v any) { s.User = v.(string) }},
+	{name: "port", get: func(s *config.Server) any { return s.GetPort() }, set: func(s *config.Server, v any) { s.Port = v.(int) }},
+	{name: "description", get: func(s *config.Server) any { return s.Description }, set: func(s *config.Server, v any) { s.Description = v.(string) }},
+	{name: "tags", get: func(s *config.Server) any { return s.Tags }, set: func(s *config.Server, v any) { s.Tags = v.([]string) }},
+	{name: "group", get: func(s *config.Server) any { return s.Group }, set: func(s *config.Server, v any) { s.Group = v.(string) }},
+	{name: "auth.type", get: func(s *config.Server) any { return s.Auth.Type }, set: func(s *config.Server, v any) { s.Auth.Type = v.(string) }},
+	{name: "auth.password", secret: true, get: func(s *config.Server) any { return s.Auth.Password }, set: func(s *config.Server, v any) { s.Auth.Password = v.(string) }},
+	{name: "auth.password_cmd", get: func(s *config.Server) any { return s.Auth.PasswordCmd }, set: func(s *config.Server, v any) { s.Auth.PasswordCmd = v.(string) }},
+	{name: "auth.identity_file", get: func(s *config.Server) any { return s.Auth.IdentityFile }, set: func(s *config.Server, v any) { s.Auth.IdentityFile = v.(string) }},
+	{name: "auth.passphrase_cmd", get: func(s *config.Server) any { return s.Auth.PassphraseCmd }, set: func(s *config.Server, v any) { s.Auth.PassphraseCmd = v.(string) }},
+	{name: "jump", get: func(s *config.Server) any { return s.Jump }, set: func(s *config.Server, v any) { s.Jump = v.([]string) }},
+	{name: "forwards", get: func(s *config.Server) any { return s.Forwards }, set: func(s *config.Server, v any) { s.Forwards = v.([]config.Forward) }},
+}
+
+// Merge 以 base 为共同祖先对本地和远程的服务器列表做三方合并
+// 只有一方修改的字段直接采用修改方的值，双方都修改且不同的字段交给 resolve 决定
+// base 为 nil（从未同步过）时，两边都有的服务器按字段比较，不同的字段视为冲突
+func Merge(base, local, remote []config.Server, resolve Resolver) (*MergeResult, error) {
+	baseMap := indexServers(base)
+	localMap := indexServers(local)
+	remoteMap := indexServers(remote)
+
+	// 保持本地顺序，远程新增的服务器追加在后面
+	var names []string
+	for _, s := range local {
+		names = append(names, s.Name)
+	}
+	for _, s := range remote {
+		if _, ok := localMap[s.Name]; !ok {
+			names = append(names, s.Name)
+		}
+	}
+
+	result := &MergeResult{Servers: []config.Server{}}
+	for _, name := range names {
+		b, inBase := baseMap[name]
+		l, inLocal := localMap[name]
+		r, inRemote := remoteMap[name]
+
+		switch {
+		case inLocal && !inRemote:
+			if !inBase {
+				// 本地新增
+				result.Servers = append(result.Servers, l)
+				continue
+			}
+			if serversEqual(l, b) {
+				// 远程已删除，本地未修改
+				continue
+			}
+			c := Conflict{Server: name, Local: "已修改", Remote: "已删除"}
+			useLocal, err := resolve(c)
+			if err != nil {
+				return nil, err
+			}
+			result.Conflicts = append(result.Conflicts, c)
+			if useLocal {
+				result.Servers = append(result.Servers, l)
+			}
+
+		case inRemote && !inLocal:
+			if !inBase {
+				// 远程新增
+				result.Servers = append(result.Servers, r)
+				continue
+			}
+			if serversEqual(r, b) {
+				// 本地已删除，远程未修改
+				continue
+			}
+			c := Conflict{Server: name, Local: "已删除", Remote: "已修改"}
+			useLocal, err := resolve(c)
+			if err != nil {
+				return nil, err
+			}
+			result.Conflicts = append(result.Conflicts, c)
+			if !useLocal {
+				result.Servers = append(result.Servers, r)
+			}
+
+		default:
+			var basePtr *config.Server
+			if inBase {
+				basePtr = &b
+			}
+			merged, conflicts, err := mergeServer(basePtr, l, r, resolve)
+			if err != nil {
+				return nil, err
+			}
+			result.Conflicts = append(result.Conflicts, conflicts...)
+			result.Servers = append(result.Servers, merged)
+		}
+	}
+	return result, nil
+}
+
+// mergeServer 逐字段合并同一台服务器
+func mergeServer(base *config.Server, local, remote config.Server, resolve Resolver) (config.Server, []Conflict, error) {
+	merged := local
+	var conflicts []Conflict
+
+	for _, f := range mergeFields {
+		lv, rv := f.get(&local), f.get(&remote)
+		if valuesEqual(lv, rv) {
+			continue
+		}
+		if base != nil {
+			bv := f.get(base)
+			if valuesEqual(lv, bv) {
+				f.set(&merged, rv)
+				continue
+			}
+			if valuesEqual(rv, bv) {
+				continue
+			}
+		}
+
+		c := Conflict{Server: local.Name, Field: f.name, Local: formatValue(lv, f.secret), Remote: formatValue(rv, f.secret)}
+		useLocal, err := resolve(c)
+		if err != nil {
+			return merged, nil, err
+		}
+		conflicts = append(conflicts, c)
+		if !useLocal {
+			f.set(&merged, rv)
+		}
+	}
+
+	if remote.LastUsed > merged.LastUsed {
+		merged.LastUsed = remote.LastUsed
+	}
+	if merged.CreatedAt == "" {
+		merged.CreatedAt = remote.CreatedAt
+	}
+	return merged, conflicts, nil
+}
+
+// PreferResolver 按 --prefer 自动解决冲突；prefer 为空时在终端逐个询问，非交互环境下报错
+func PreferResolver(prefer string) (Resolver, error) {
+	switch prefer {
+	case PreferLocal:
+		return func(Conflict) (bool, error) { return true, nil }, nil
+	case PreferRemote:
+		return func(Conflict) (bool, error) { return false, nil }, nil
+	case "":
+	default:
+		return nil, fmt.Errorf("--prefer 只能是 local 或 remote: %s", prefer)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	return func(c Conflict) (bool, error) {
+		if !util.IsTerminal() {
+			return false, fmt.Errorf("合并冲突 %s\n请在终端中运行以交互解决，或使用 --prefer local|remote", c)
+		}
+		for {
+			fmt.Printf("冲突 %s\n保留哪一方? (l=本地 / r=远程): ", c)
+			answer, err := reader.ReadString('\n')
+			if err != nil {
+				return false, fmt.Errorf("读取输入失败: %w", err)
+			}
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "l", "local":
+				return true, nil
+			case "r", "remote":
+				return false, nil
+			}
+		}
+	}, nil
+}
+
+// indexServers 按名称索引服务器
+func indexServers(servers []config.Server) map[string]config.Server {
+	m := make(map[string]config.Server, len(servers))
+	for _, s := range servers {
+		m[s.Name] = s
+	}
+	return m
+}
+
+// serversEqual 判断两台服务器参与合并的字段是否完全相同
+func serversEqual(a, b config.Server) bool {
+	for _, f := range mergeFields {
+		if !valuesEqual(f.get(&a), f.get(&b)) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual 比较字段值，nil 与空切片视为相同
+func valuesEqual(a, b any) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Slice && vb.Kind() == reflect.Slice && va.Len() == 0 && vb.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// formatValue 格式化字段值用于展示，密码只显示是否设置
+func formatValue(v any, secret bool) string {
+	if secret {
+		if v.(string) == "" {
+			return "(空)"
+		}
+		return "******"
+	}
+	switch val := v.(type) {
+	case string:
+		if val == "" {
+			return "(空)"
+		}
+		return val
+	case []string:
+		if len(val) == 0 {
+			return "(空)"
+		}
+		return strings.Join(val, ",")
+	case []config.Forward:
+		names := make([]string, 0, len(val))
+		for _, fw := range val {
+			names = append(names, fmt.Sprintf("%s(%s %s->%s)", fw.Name, fw.Type, fw.Listen, fw.Target))
+		}
+		if len(names) == 0 {
+			return "(空)"
+		}
+		return strings.Join(names, ",")
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -1,12 +1,18 @@
 package sync
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/util"
 )
 
 // Sync 同步接口
@@ -20,9 +26,12 @@ func NewSync(cfg *config.SyncConfig) (Sync, error) {
 	switch cfg.Type {
 	case "ssh":
 		return NewSSHSync(cfg), nil
-	case "http":
-		// 未来实现
-		return nil, fmt.Errorf("HTTP同步尚未实现")
+	case "http", "https":
+		return NewHTTPSync(cfg), nil
+	case "git":
+		return NewGitSync(cfg), nil
+	case "webdav":
+		return NewWebDAVSync(cfg), nil
 	case "ftp":
 		// 未来实现
 		return nil, fmt.Errorf("FTP同步尚未实现")
@@ -31,111 +40,699 @@ func NewSync(cfg *config.SyncConfig) (Sync, error) {
 	}
 }
 
-// Pull 从云端拉取配置
-func Pull() error {
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("加载配置失败: %w", err)
+// Options pull / push 的选项
+type Options struct {
+	Prefer  string        // 合并冲突时优先使用的一方（local / remote），为空时交互询问
+	Quiet   bool          // 后台自动同步：不输出诊断信息，不在终端询问（冲突、主机密钥、口令等直接报错）
+	Timeout time.Duration // 访问同步后端的超时时间，0 表示不限制
+	DryRun  bool          // 只计算差异，不写入本地和远程；未指定 Prefer 时冲突保留本地的值
+	Yes     bool          // 拉取会删除本地服务器时不再确认
+	Profile string        // 只同步指定的同步目标（default 或 sync profile 名称），为空时同步所有已启用的目标
+}
+
+// ErrRemoteNotFound 远程还没有配置文件（从未推送过）
+var ErrRemoteNotFound = errors.New("远程配置不存在")
+
+// syncResult 一次 pull / push 的结果
+type syncResult struct {
+	before    int            // 合并前本地的服务器数量
+	servers   int            // 合并后该同步目标的服务器数量
+	conflicts []Conflict     // 解决的冲突
+	changes   []ServerChange // 本地（pull）或远程（push）的变更
+	canceled  bool           // 用户取消了删除服务器的拉取
+}
+
+// Pull 从云端拉取配置，与本地修改做三方合并
+func Pull(opts Options) error {
+	return eachTarget(opts, func(name, label string) error {
+		result, err := pull(name, opts)
+		if err != nil {
+			return err
+		}
+		if result.canceled {
+			fmt.Printf("%s已取消拉取，本地配置未修改\n", label)
+			return nil
+		}
+
+		fmt.Printf("%s配置拉取成功，合并后共 %d 个服务器配置（之前 %d 个）", label, result.servers, result.before)
+		if len(result.conflicts) > 0 {
+			fmt.Printf("，解决了 %d 处冲突", len(result.conflicts))
+		}
+		fmt.Println()
+		return nil
+	})
+}
+
+// Push 推送配置到云端：先获取远程配置做三方合并，避免覆盖其他人推送的修改
+func Push(opts Options) error {
+	return eachTarget(opts, func(name, label string) error {
+		result, err := push(name, opts)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s配置推送成功，推送了 %d 个服务器配置", label, result.servers)
+		if len(result.conflicts) > 0 {
+			fmt.Printf("，解决了 %d 处冲突", len(result.conflicts))
+		}
+		fmt.Println()
+		return nil
+	})
+}
+
+// PreviewPull 预览拉取对本地配置的修改，不写入任何内容
+// 多个同步目标中有目标失败时，同时返回其余目标的预览和错误
+func PreviewPull(opts Options) ([]*Plan, error) {
+	return preview(opts, "pull", pull)
+}
+
+// PreviewPush 预览推送对远程配置的修改，不写入任何内容
+func PreviewPush(opts Options) ([]*Plan, error) {
+	return preview(opts, "push", push)
+}
+
+// preview 对每个同步目标执行 pull / push 的预览
+func preview(opts Options, direction string, run func(string, Options) (*syncResult, error)) ([]*Plan, error) {
+	opts.DryRun = true
+	var plans []*Plan
+	err := eachTarget(opts, func(name, label string) error {
+		result, err := run(name, opts)
+		if err != nil {
+			return err
+		}
+		conflicts := result.conflicts
+		if conflicts == nil {
+			conflicts = []Conflict{}
+		}
+		plans = append(plans, &Plan{Profile: name, Direction: direction, Changes: result.changes, Conflicts: conflicts})
+		return nil
+	})
+	// 部分同步目标失败时仍返回其他目标的预览
+	return plans, err
+}
+
+// targetNames 返回本次同步的目标：指定了 Profile 时只有该目标，否则为所有已启用的目标
+// 后台自动同步只包含开启了 auto_sync 的目标
+func targetNames(cfg *config.Config, opts Options) ([]string, error) {
+	if err := cfg.ValidateSyncProfiles(); err != nil {
+		return nil, err
 	}
 
-	if !cfg.Sync.Enabled {
-		return fmt.Errorf("同步功能未启用，请先运行 'gssh init' 配置同步设置")
+	if opts.Profile != "" {
+		sc, err := cfg.SyncTarget(opts.Profile)
+		if err != nil {
+			return nil, err
+		}
+		if !sc.Enabled {
+			return nil, fmt.Errorf("同步目标 %s 未启用（enabled: false）", opts.Profile)
+		}
+		return []string{opts.Profile}, nil
 	}
 
-	// 验证同步配置
-	if cfg.Sync.SSHHost == "" {
-		return fmt.Errorf("同步配置不完整：缺少 ssh_host，请运行 'gssh init' 重新配置")
+	var names []string
+	if cfg.Sync.Enabled && (!opts.Quiet || cfg.Sync.AutoSync) {
+		names = append(names, config.DefaultSyncTarget)
 	}
-	if cfg.Sync.SSHUser == "" {
-		return fmt.Errorf("同步配置不完整：缺少 ssh_user，请运行 'gssh init' 重新配置")
+	for _, p := range cfg.SyncProfiles {
+		if p.Enabled && (!opts.Quiet || p.AutoSync) {
+			names = append(names, p.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("同步功能未启用，请先运行 'gssh init' 配置同步设置")
 	}
-	if cfg.Sync.SSHKey == "" && cfg.Sync.Password == "" {
-		return fmt.Errorf("同步配置不完整：缺少 ssh_key 或 password，请运行 'gssh init' 重新配置或手动编辑配置文件")
+	return names, nil
+}
+
+// eachTarget 依次对每个同步目标执行 run，label 为输出时的前缀（没有 sync profile 时为空）
+// 只有一个目标时直接返回其错误；多个目标时某个目标失败不影响其他目标，最后汇总
+func eachTarget(opts Options, run func(name, label string) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	names, err := targetNames(cfg, opts)
+	if err != nil {
+		return err
 	}
 
-	// 检查密钥文件是否存在
-	if cfg.Sync.SSHKey != "" {
-		keyPath := cfg.Sync.SSHKey
-		if keyPath[0] == '~' {
-			homeDir, _ := os.UserHomeDir()
-			keyPath = filepath.Join(homeDir, keyPath[1:])
+	var failed []string
+	var first error
+	for _, name := range names {
+		label := ""
+		if len(cfg.SyncProfiles) > 0 {
+			label = "[" + name + "] "
+		}
+		err := run(name, label)
+		if err == nil {
+			continue
+		}
+		if len(names) == 1 {
+			return err
+		}
+		if first == nil {
+			first = fmt.Errorf("%s%w", label, err)
 		}
-		if _, err := os.Stat(keyPath); os.IsNotExist(err) {
-			return fmt.Errorf("密钥文件不存在: %s，请检查路径或运行 'gssh init' 重新配置", cfg.Sync.SSHKey)
+		failed = append(failed, name)
+		if !opts.Quiet {
+			fmt.Fprintf(os.Stderr, "%s错误: %v\n", label, err)
 		}
 	}
+	if opts.Quiet && first != nil {
+		// 后台同步不输出，返回第一个错误用于显示
+		return first
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d 个同步目标失败: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
 
-	sync, err := NewSync(&cfg.Sync)
+// pull 拉取同步目标 name 的远程配置，与本地属于该目标的服务器合并
+func pull(name string, opts Options) (*syncResult, error) {
+	cfg, sc, err := loadTarget(name)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := validateSyncConfig(sc); err != nil {
+		return nil, err
 	}
 
-	remoteCfg, err := sync.Pull()
+	resolve, err := resolver(opts)
 	if err != nil {
+		return nil, err
+	}
+	if err := checkQuiet(cfg, name, opts); err != nil {
+		return nil, err
+	}
+
+	sync, err := newBackend(sc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteCfg, err := withTimeout(opts.Timeout, sync.Pull)
+	if err != nil && sc.Type == "ssh" && !errors.Is(err, ErrRemoteNotFound) && !opts.Quiet {
 		// 提供详细的诊断信息
 		fmt.Fprintf(os.Stderr, "\n诊断信息：\n")
-		fmt.Fprintf(os.Stderr, "  同步服务器: %s\n", cfg.Sync.SSHHost)
-		fmt.Fprintf(os.Stderr, "  SSH 用户: %s\n", cfg.Sync.SSHUser)
-		if cfg.Sync.SSHKey != "" {
-			fmt.Fprintf(os.Stderr, "  密钥路径: %s\n", cfg.Sync.SSHKey)
+		if sc.SSHPort != 0 && sc.SSHPort != 22 {
+			fmt.Fprintf(os.Stderr, "  同步服务器: %s（端口 %d）\n", sc.SSHHost, sc.SSHPort)
+		} else {
+			fmt.Fprintf(os.Stderr, "  同步服务器: %s\n", sc.SSHHost)
+		}
+		fmt.Fprintf(os.Stderr, "  SSH 用户: %s\n", sc.SSHUser)
+		if sc.SSHKey != "" {
+			fmt.Fprintf(os.Stderr, "  密钥路径: %s\n", sc.SSHKey)
 		} else {
 			fmt.Fprintf(os.Stderr, "  认证方式: 密码\n")
 		}
 		fmt.Fprintf(os.Stderr, "\n请检查：\n")
-		if cfg.Sync.SSHKey != "" {
+		if sc.SSHKey != "" {
 			fmt.Fprintf(os.Stderr, "  1. 密钥文件是否存在且可读\n")
 			fmt.Fprintf(os.Stderr, "  2. 密钥文件权限是否正确（建议 600）\n")
 		}
 		fmt.Fprintf(os.Stderr, "  3. 是否可以手动 SSH 连接到同步服务器\n")
 		fmt.Fprintf(os.Stderr, "  4. 运行 'gssh init' 重新配置同步设置\n\n")
-		return fmt.Errorf("拉取配置失败: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("拉取配置失败: %w", err)
+	}
+	remoteServers, err := openServers(&sc.Encryption, remoteCfg, opts.Quiet)
+	if err != nil {
+		return nil, fmt.Errorf("拉取配置失败: %w", err)
+	}
+	// 合并与确认可能需要等待终端输入，在配置锁外进行；保存前在锁内确认本地没有被其他进程修改，否则重新合并
+	var res *syncResult
+	for attempt := 1; ; attempt++ {
+		res, err = mergePull(name, sc, remoteServers, resolve, opts)
+		if errors.Is(err, errLocalChanged) && attempt < mergeAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if opts.DryRun || res.canceled {
+		return res, nil
 	}
 
-	// 只使用远程的 servers，保留本地的 sync 配置
-	cfg.Servers = remoteCfg.Servers
-	cfg.Version = remoteCfg.Version
-	cfg.Sync.LastSync = getCurrentTime()
+	// 合并结果中可能还有未推送的本地修改，基线记录远程当前的内容
+	if err := saveSyncBase(name, remoteServers); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
 
-	if err := config.Save(cfg); err != nil {
-		return fmt.Errorf("保存配置失败: %w", err)
+// mergePull 将本地属于同步目标 name 的服务器与远程合并，确认后保存到本地配置
+func mergePull(name string, sc *config.SyncConfig, remoteServers []config.Server, resolve Resolver, opts Options) (*syncResult, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	owned := ownedServers(cfg, name)
+	result, err := mergeWithBase(name, owned, remoteServers, resolve)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Printf("配置拉取成功，更新了 %d 个服务器配置\n", len(remoteCfg.Servers))
-	return nil
+	local, err := config.RevealServers(owned)
+	if err != nil {
+		return nil, fmt.Errorf("解密服务器密码失败: %w", err)
+	}
+	res := &syncResult{before: len(owned), servers: len(result.Servers), conflicts: result.Conflicts, changes: Diff(local, result.Servers)}
+	if _, err := replaceOwned(cfg, name, result.Servers); err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+	if removed := removedServers(res.changes); len(removed) > 0 && !opts.Quiet && !opts.Yes {
+		ok, err := confirmRemoval(removed)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			res.canceled = true
+			return res, nil
+		}
+	}
+
+	// 只更新该目标的服务器，保留本地的 sync 配置和其他目标的服务器
+	if err := commitMerge(name, owned, result.Servers, sc); err != nil {
+		return nil, err
+	}
+	if !opts.Quiet {
+		warnUnowned(cfg, name, result.Servers)
+	}
+	return res, nil
+}
+
+// push 合并同步目标 name 的远程配置后推送该目标的服务器，本地同样更新为合并结果
+func push(name string, opts Options) (*syncResult, error) {
+	cfg, sc, err := loadTarget(name)
+	if err != nil {
+		return nil, err
+	}
+
+	resolve, err := resolver(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkQuiet(cfg, name, opts); err != nil {
+		return nil, err
+	}
+
+	sync, err := newBackend(sc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := &pushRemote{servers: []config.Server{}}
+	remote.before = remote.servers // 远程当前的服务器列表，用于计算推送的变更
+	remoteCfg, err := withTimeout(opts.Timeout, sync.Pull)
+	switch {
+	case errors.Is(err, ErrRemoteNotFound):
+		// 第一次推送
+	case err != nil:
+		return nil, fmt.Errorf("获取远程配置失败: %w", err)
+	default:
+		remote.servers, err = openServers(&sc.Encryption, remoteCfg, opts.Quiet)
+		if errors.Is(err, errPlainRemote) {
+			// 明文内容无法确认来源，不参与合并，直接用本地服务器列表加密覆盖
+			if !opts.Quiet {
+				fmt.Fprintln(os.Stderr, "提示: 远程配置还是明文，已忽略其内容，本次推送后将只保存本地服务器列表的密文")
+			}
+			remote.servers, err, remote.ignore = nil, nil, true
+			remote.before = remoteCfg.Servers
+		}
+		if err != nil {
+			return nil, fmt.Errorf("获取远程配置失败: %w", err)
+		}
+		if !remote.ignore {
+			remote.before = remote.servers
+		}
+		remote.history = remoteCfg.History
+	}
+	if !remote.ignore {
+		if remote.before, err = config.RevealServers(remote.before); err != nil {
+			return nil, fmt.Errorf("解密服务器密码失败: %w", err)
+		}
+	}
+
+	// 合并在配置锁外进行（冲突时可能等待终端输入），保存前在锁内确认本地没有被其他进程修改，否则重新合并
+	var (
+		res     *syncResult
+		merged  []config.Server
+		pushCfg *config.Config
+	)
+	for attempt := 1; ; attempt++ {
+		res, merged, pushCfg, err = mergePush(name, sc, remote, resolve, opts)
+		if errors.Is(err, errLocalChanged) && attempt < mergeAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+
+	// 本地已保存合并结果，推送在配置锁外进行，不会在网络较慢时阻塞其他 gssh 进程
+	// 推送失败时本地相当于做了一次拉取，基线不变，下次推送重新合并即可
+	if _, err := withTimeout(opts.Timeout, func() (struct{}, error) { return struct{}{}, sync.Push(pushCfg) }); err != nil {
+		return nil, fmt.Errorf("推送配置失败: %w", err)
+	}
+	// 记录推送后的远程版本和最后同步时间
+	if _, err := config.Update(func(latest *config.Config) error {
+		return commitTarget(latest, name, sc)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := saveSyncBase(name, merged); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// pushRemote 推送前获取的远程内容
+type pushRemote struct {
+	servers []config.Server       // 参与合并的远程服务器列表
+	before  []config.Server       // 远程当前的服务器列表（已解密），用于计算推送的变更
+	history []config.HistoryEntry // 远程保存的历史版本
+	ignore  bool                  // 远程是明文，不参与合并
 }
 
-// Push 推送配置到云端
-func Push() error {
+// mergePush 将本地属于同步目标 name 的服务器与远程合并并保存到本地配置，返回合并结果和要推送的配置
+func mergePush(name string, sc *config.SyncConfig, remote *pushRemote, resolve Resolver, opts Options) (*syncResult, []config.Server, *config.Config, error) {
 	cfg, err := config.Load()
 	if err != nil {
-		return fmt.Errorf("加载配置失败: %w", err)
+		return nil, nil, nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	owned := ownedServers(cfg, name)
+	remoteServers := remote.servers
+	if remote.ignore {
+		remoteServers = owned
+	}
+
+	result, err := mergeWithBase(name, owned, remoteServers, resolve)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	res := &syncResult{before: len(owned), servers: len(result.Servers), conflicts: result.Conflicts, changes: Diff(remote.before, result.Servers)}
+	if _, err := replaceOwned(cfg, name, result.Servers); err != nil {
+		return nil, nil, nil, err
+	}
+	if opts.DryRun {
+		return res, nil, nil, nil
 	}
 
-	if !cfg.Sync.Enabled {
-		return fmt.Errorf("同步功能未启用，请先配置同步设置")
+	pushCfg, err := newPushConfig(cfg, sc, result.Servers, remote.history, "", opts.Quiet)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// 本地同样使用合并结果
+	if err := commitMerge(name, owned, result.Servers, nil); err != nil {
+		return nil, nil, nil, err
 	}
+	return res, result.Servers, pushCfg, nil
+}
+
+// errLocalChanged 合并期间本地属于该同步目标的服务器被其他进程修改
+var errLocalChanged = errors.New("合并期间本地配置被其他 gssh 进程修改，请重新运行")
+
+// mergeAttempts 本地配置在合并期间被修改时最多合并的次数
+const mergeAttempts = 3
+
+// commitMerge 在配置锁内用合并结果替换本地属于同步目标 name 的服务器
+// owned 为合并时本地的服务器列表，期间被其他进程修改时返回 errLocalChanged；sc 不为 nil 时同时记录同步状态
+func commitMerge(name string, owned, merged []config.Server, sc *config.SyncConfig) error {
+	_, err := config.Update(func(latest *config.Config) error {
+		if !reflect.DeepEqual(ownedServers(latest, name), owned) {
+			return errLocalChanged
+		}
+		servers, err := replaceOwned(latest, name, merged)
+		if err != nil {
+			return err
+		}
+		latest.Servers = servers
+		if sc == nil {
+			return nil
+		}
+		return commitTarget(latest, name, sc)
+	})
+	return err
+}
 
-	sync, err := NewSync(&cfg.Sync)
+// loadTarget 加载本地配置，返回同步目标 name 的同步配置（指向 cfg 内部，修改后随 cfg 保存）
+func loadTarget(name string) (*config.Config, *config.SyncConfig, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	if err := cfg.ValidateSyncProfiles(); err != nil {
+		return nil, nil, err
+	}
+	sc, err := cfg.SyncTarget(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !sc.Enabled {
+		if name == config.DefaultSyncTarget {
+			return nil, nil, fmt.Errorf("同步功能未启用，请先运行 'gssh init' 配置同步设置")
+		}
+		return nil, nil, fmt.Errorf("同步目标 %s 未启用（enabled: false）", name)
+	}
+	return cfg, sc, nil
+}
+
+// ownedServers 返回本地属于同步目标 name 的服务器
+func ownedServers(cfg *config.Config, name string) []config.Server {
+	owned := []config.Server{}
+	for i := range cfg.Servers {
+		if cfg.SyncOwner(&cfg.Servers[i]) == name {
+			owned = append(owned, cfg.Servers[i])
+		}
+	}
+	return owned
+}
+
+// replaceOwned 用合并结果替换本地属于同步目标 name 的服务器，其他服务器保持不变
+// 保持本地原有顺序，新增的服务器追加在后面；与其他目标的服务器重名时报错，不修改本地配置
+func replaceOwned(cfg *config.Config, name string, merged []config.Server) ([]config.Server, error) {
+	mergedMap := indexServers(merged)
+	placed := make(map[string]bool, len(merged))
+
+	servers := []config.Server{}
+	for i := range cfg.Servers {
+		s := cfg.Servers[i]
+		if cfg.SyncOwner(&s) == name {
+			if m, ok := mergedMap[s.Name]; ok && !placed[s.Name] {
+				m.Source = s.Source // 留在原来的服务器文件中
+				servers = append(servers, m)
+				placed[s.Name] = true
+			}
+			continue
+		}
+		if _, ok := mergedMap[s.Name]; ok {
+			return nil, fmt.Errorf("同步目标 %s 中的服务器 %s 与本地属于 %s 的服务器重名，请先重命名其中一个", name, s.Name, cfg.SyncOwner(&s))
+		}
+		servers = append(servers, s)
+	}
+	for _, m := range merged {
+		if !placed[m.Name] {
+			servers = append(servers, m)
+			placed[m.Name] = true
+		}
+	}
+	return servers, nil
+}
+
+// warnUnowned 提示拉取到的服务器不符合该目标的筛选条件（之后会由其他目标同步）
+func warnUnowned(cfg *config.Config, name string, servers []config.Server) {
+	for i := range servers {
+		if owner := cfg.SyncOwner(&servers[i]); owner != name {
+			fmt.Fprintf(os.Stderr, "提示: 拉取到的服务器 %s 不符合 %s 的 groups/tags，本地将由 %s 同步\n", servers[i].Name, name, owner)
+		}
+	}
+}
+
+// newBackend 创建同步后端，后台同步时禁止后端在终端询问
+func newBackend(cfg *config.SyncConfig, opts Options) (Sync, error) {
+	s, err := NewSync(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Quiet {
+		switch b := s.(type) {
+		case *SSHSync:
+			b.quiet = true
+		case *GitSync:
+			b.quiet = true
+		case *HTTPSync:
+			b.quiet = true
+		case *WebDAVSync:
+			b.quiet = true
+		}
+	}
+	return s, nil
+}
+
+// resolver 返回合并冲突的处理方式，后台同步且未指定 --prefer 时遇到冲突直接失败
+// 预览且未指定 --prefer 时不询问，冲突保留本地的值
+func resolver(opts Options) (Resolver, error) {
+	if opts.DryRun && opts.Prefer == "" {
+		return func(Conflict) (bool, error) { return true, nil }, nil
+	}
+	if opts.Quiet && opts.Prefer == "" {
+		return func(c Conflict) (bool, error) {
+			return false, fmt.Errorf("合并冲突 %s，请运行 'gssh pull' 解决", c)
+		}, nil
+	}
+	return PreferResolver(opts.Prefer)
+}
+
+// confirmRemoval 拉取会删除本地服务器时在终端确认，非交互环境下需要 -y
+func confirmRemoval(names []string) (bool, error) {
+	if !util.IsTerminal() {
+		return false, fmt.Errorf("拉取会删除本地的 %d 个服务器: %s\n请在终端中确认，或使用 -y 跳过确认（--dry-run 可预览全部变更）", len(names), strings.Join(names, ", "))
+	}
+	fmt.Printf("拉取会删除本地的 %d 个服务器: %s\n", len(names), strings.Join(names, ", "))
+	fmt.Print("继续? (y/N): ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return util.IsYes(strings.TrimSpace(answer)), nil
+}
+
+// commitTarget 将本次同步的状态（后端记录的 ETag 和最后同步时间）写入最新配置中的同步目标
+func commitTarget(latest *config.Config, name string, sc *config.SyncConfig) error {
+	target, err := latest.SyncTarget(name)
 	if err != nil {
 		return err
 	}
+	target.ETag = sc.ETag
+	target.LastSync = getCurrentTime()
+	return nil
+}
 
-	// 创建只包含 servers 的配置用于推送（不包含 sync 配置）
-	pushCfg := &config.Config{
-		Version: cfg.Version,
-		Servers: cfg.Servers,
-		// Sync 部分不推送，由各客户端自己维护
+// withTimeout 执行访问同步后端的操作，超过 timeout 后不再等待（timeout 为 0 表示不限制）
+// 超时后操作仍会在后台运行完毕，但结果被丢弃，不会写入本地配置
+func withTimeout[T any](timeout time.Duration, fn func() (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn()
 	}
 
-	if err := sync.Push(pushCfg); err != nil {
-		return fmt.Errorf("推送配置失败: %w", err)
+	type result struct {
+		value T
+		err   error
 	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
 
-	// 更新最后同步时间
-	cfg.Sync.LastSync = getCurrentTime()
-	config.Save(cfg)
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-time.After(timeout):
+		var zero T
+		return zero, fmt.Errorf("连接同步后端超时（%s）", timeout)
+	}
+}
+
+// mergeWithBase 读取同步目标 name 的基线快照，将本地与远程的服务器列表做三方合并
+// 保险库中的密码会先解密再比较，合并结果中的密码为明文，保存本地配置时会重新加密
+func mergeWithBase(name string, local, remote []config.Server, resolve Resolver) (*MergeResult, error) {
+	basePath, err := config.GetSyncBasePath(name)
+	if err != nil {
+		return nil, err
+	}
+	base, err := config.LoadSnapshot(basePath)
+	if err != nil {
+		return nil, err
+	}
+	if base, err = config.RevealServers(base); err != nil {
+		return nil, fmt.Errorf("解密服务器密码失败: %w", err)
+	}
+	if local, err = config.RevealServers(local); err != nil {
+		return nil, fmt.Errorf("解密服务器密码失败: %w", err)
+	}
+	if remote, err = config.RevealServers(remote); err != nil {
+		return nil, fmt.Errorf("解密服务器密码失败: %w", err)
+	}
 
-	fmt.Printf("配置推送成功，推送了 %d 个服务器配置\n", len(cfg.Servers))
+	return Merge(base, local, remote, resolve)
+}
+
+// saveSyncBase 记录同步目标 name 最后一次同步后双方共同的服务器列表（与推送的内容一致，不包含模板）
+func saveSyncBase(name string, servers []config.Server) error {
+	basePath, err := config.GetSyncBasePath(name)
+	if err != nil {
+		return err
+	}
+	if err := config.SaveSnapshot(basePath, flattenServers(servers)); err != nil {
+		return fmt.Errorf("保存同步基线失败: %w", err)
+	}
+	return nil
+}
+
+// flattenServers 去掉服务器引用的模板名称
+// 同步后端和基线中保存的都是展开 defaults / 模板之后的实际配置，模板只在本地有意义
+func flattenServers(servers []config.Server) []config.Server {
+	flat := make([]config.Server, len(servers))
+	for i, s := range servers {
+		s.Template = ""
+		flat[i] = s
+	}
+	return flat
+}
+
+// validateSyncConfig 检查同步配置是否完整
+func validateSyncConfig(cfg *config.SyncConfig) error {
+	switch cfg.Type {
+	case "ssh":
+		if cfg.SSHHost == "" {
+			return fmt.Errorf("同步配置不完整：缺少 ssh_host，请运行 'gssh init' 重新配置")
+		}
+		if cfg.SSHUser == "" {
+			return fmt.Errorf("同步配置不完整：缺少 ssh_user，请运行 'gssh init' 重新配置")
+		}
+		if cfg.SSHPort < 0 || cfg.SSHPort > 65535 {
+			return fmt.Errorf("同步配置无效：ssh_port 必须在 1-65535 之间: %d", cfg.SSHPort)
+		}
+		if cfg.SSHKey == "" && cfg.Password == "" && cfg.PasswordCmd == "" {
+			return fmt.Errorf("同步配置不完整：缺少 ssh_key、password 或 password_cmd，请运行 'gssh init' 重新配置或手动编辑配置文件")
+		}
+
+		// 检查密钥文件是否存在
+		if cfg.SSHKey != "" {
+			keyPath := cfg.SSHKey
+			if keyPath[0] == '~' {
+				homeDir, _ := os.UserHomeDir()
+				keyPath = filepath.Join(homeDir, keyPath[1:])
+			}
+			if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+				return fmt.Errorf("密钥文件不存在: %s，请检查路径或运行 'gssh init' 重新配置", cfg.SSHKey)
+			}
+		}
+	case "git":
+		if cfg.GitRepo == "" {
+			return fmt.Errorf("同步配置不完整：缺少 git_repo，请运行 'gssh init' 重新配置")
+		}
+		if _, err := exec.LookPath("git"); err != nil {
+			return fmt.Errorf("Git 同步需要系统安装 git 命令")
+		}
+	case "http", "https", "webdav":
+		if cfg.URL == "" {
+			return fmt.Errorf("同步配置不完整：缺少 url，请运行 'gssh init' 重新配置")
+		}
+		if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+			return fmt.Errorf("同步地址必须以 http:// 或 https:// 开头: %s", cfg.URL)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,142 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/vault"
+)
+
+// useTempHome 将配置目录和解锁缓存目录指向临时目录
+func useTempHome(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+}
+
+// createVault 创建并保存保险库，解锁缓存为该保险库的私钥
+func createVault(t *testing.T, passphrase string) (*vault.Vault, *[32]byte) {
+	t.Helper()
+	v, err := vault.Create(passphrase)
+	if err != nil {
+		t.Fatalf("创建保险库失败: %v", err)
+	}
+	if err := v.Save(); err != nil {
+		t.Fatalf("保存保险库失败: %v", err)
+	}
+	return v, unlock(t, v, passphrase)
+}
+
+// unlock 清除进程内的解锁状态，缓存并返回 v 的私钥
+func unlock(t *testing.T, v *vault.Vault, passphrase string) *[32]byte {
+	t.Helper()
+	if err := vault.Lock(); err != nil {
+		t.Fatalf("锁定保险库失败: %v", err)
+	}
+	priv, err := v.Unlock(passphrase)
+	if err != nil {
+		t.Fatalf("解锁保险库失败: %v", err)
+	}
+	if err := vault.SaveSession(priv, time.Hour); err != nil {
+		t.Fatalf("缓存解锁状态失败: %v", err)
+	}
+	return priv
+}
+
+func TestPullAfterVaultRekey(t *testing.T) {
+	useTempHome(t)
+	srv := newWebDAVServer(t)
+	v, priv := createVault(t, "old")
+
+	cfg := testConfig("web1", "db1")
+	cfg.Servers[0].Auth.Password = "secret"
+	cfg.Sync = config.SyncConfig{Enabled: true, Type: "webdav", URL: srv.URL, WebDAVPath: "gssh/config.yaml"}
+	if err := config.Save(cfg); err != nil {
+		t.Fatalf("保存配置失败: %v", err)
+	}
+	if err := Push(Options{}); err != nil {
+		t.Fatalf("推送失败: %v", err)
+	}
+
+	newVault, err := vault.Create("new")
+	if err != nil {
+		t.Fatalf("创建新保险库失败: %v", err)
+	}
+	if _, _, err := config.Rekey(v, priv, newVault); err != nil {
+		t.Fatalf("更换保险库密钥失败: %v", err)
+	}
+	unlock(t, newVault, "new")
+
+	// 同步基线同样使用新密钥加密，拉取、推送不受影响
+	if err := Pull(Options{}); err != nil {
+		t.Fatalf("更换密钥后拉取失败: %v", err)
+	}
+	if err := Push(Options{}); err != nil {
+		t.Fatalf("更换密钥后推送失败: %v", err)
+	}
+
+	loaded, err := config.Load()
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	servers, err := config.RevealServers(loaded.Servers)
+	if err != nil {
+		t.Fatalf("解密服务器密码失败: %v", err)
+	}
+	if got := servers[0].Auth.Password; got != "secret" {
+		t.Errorf("更换密钥后 web1 的密码 = %q，期望 secret", got)
+	}
+}
+
+func TestMergePullRetriesWhenLocalChanges(t *testing.T) {
+	useTempHome(t)
+
+	cfg := testConfig("web1")
+	cfg.Sync = config.SyncConfig{Enabled: true, Type: "webdav", URL: "http://127.0.0.1"}
+	if err := config.Save(cfg); err != nil {
+		t.Fatalf("保存配置失败: %v", err)
+	}
+	if err := saveSyncBase(config.DefaultSyncTarget, cfg.Servers); err != nil {
+		t.Fatalf("保存同步基线失败: %v", err)
+	}
+	if _, err := config.Update(func(latest *config.Config) error {
+		latest.Servers[0].Port = 2222
+		return nil
+	}); err != nil {
+		t.Fatalf("修改本地配置失败: %v", err)
+	}
+	remote := testConfig("web1").Servers
+	remote[0].Port = 2200
+
+	// 询问冲突时不持有配置锁：其他进程可以修改本地配置，保存前发现修改后放弃本次合并
+	edited := false
+	resolve := func(Conflict) (bool, error) {
+		if !edited {
+			edited = true
+			if _, err := config.Update(func(latest *config.Config) error {
+				latest.Servers[0].Description = "edited"
+				return nil
+			}); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if _, err := mergePull(config.DefaultSyncTarget, &cfg.Sync, remote, resolve, Options{}); !errors.Is(err, errLocalChanged) {
+		t.Fatalf("合并期间本地被修改时错误 = %v，期望 errLocalChanged", err)
+	}
+
+	// 重新合并时包含期间的修改
+	if _, err := mergePull(config.DefaultSyncTarget, &cfg.Sync, remote, resolve, Options{}); err != nil {
+		t.Fatalf("重新合并失败: %v", err)
+	}
+	loaded, err := config.Load()
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if s := loaded.Servers[0]; s.Port != 2222 || s.Description != "edited" {
+		t.Errorf("合并后 port = %d、description = %q，期望保留本地的 2222 和合并期间的修改", s.Port, s.Description)
+	}
+}
@@ -0,0 +1,312 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultWebDAVPath WebDAV 同步时配置文件的默认路径（相对于 url）
+const defaultWebDAVPath = "gssh/config.yaml"
+
+// webdavLockTimeout 推送期间对远程配置文件加锁的时长，推送异常中断时锁会自动过期
+const webdavLockTimeout = "Second-60"
+
+// WebDAVSync WebDAV 方式同步（NAS、Nextcloud 等）：GET 拉取，PUT 推送
+// 推送前对配置文件加写锁（LOCK），并与 HTTP 方式一样用 ETag 检测拉取之后是否有其他人推送；
+// 服务端不支持加锁时只使用 ETag。远程目录不存在时用 MKCOL 逐级创建
+type WebDAVSync struct {
+	config *config.SyncConfig
+	client *http.Client
+	quiet  bool // 后台同步：token、password_cmd 不在终端交互
+}
+
+// NewWebDAVSync 创建WebDAV同步实例
+func NewWebDAVSync(cfg *config.SyncConfig) *WebDAVSync {
+	return &WebDAVSync{
+		config: cfg,
+		client: &http.Client{Timeout: httpTimeout},
+	}
+}
+
+// Pull 从 WebDAV 服务器拉取配置，并记录 ETag
+func (s *WebDAVSync) Pull() (*config.Config, error) {
+	fileURL, err := s.fileURL()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(http.MethodGet, fileURL, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		s.config.ETag = ""
+		return nil, fmt.Errorf("%w（%s），请先运行 'gssh push'", ErrRemoteNotFound, fileURL)
+	}
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取远程配置失败: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		// 推送中断时可能留下加锁创建的空文件
+		s.config.ETag = ""
+		return nil, fmt.Errorf("%w（%s 为空），请先运行 'gssh push'", ErrRemoteNotFound, fileURL)
+	}
+
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析远程配置失败: %w", err)
+	}
+
+	s.config.ETag = resp.Header.Get("ETag")
+	return &cfg, nil
+}
+
+// Push 推送配置到 WebDAV 服务器
+// 加锁成功后先确认远程版本与最后一次同步的 ETag 一致再写入，写入完成后解锁
+func (s *WebDAVSync) Push(cfg *config.Config) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	fileURL, err := s.fileURL()
+	if err != nil {
+		return err
+	}
+
+	token, err := s.lock(fileURL)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"Content-Type": "application/yaml"}
+	if token != "" {
+		defer s.unlock(fileURL, token)
+		if err := s.checkVersion(fileURL); err != nil {
+			return err
+		}
+		headers["If"] = "(" + token + ")"
+	} else if s.config.ETag != "" {
+		headers["If-Match"] = s.config.ETag
+	} else {
+		headers["If-None-Match"] = "*"
+	}
+
+	resp, err := s.do(http.MethodPut, fileURL, data, headers)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusConflict && token == "" {
+		// 上级目录不存在（加锁成功时目录一定已存在）
+		resp.Body.Close()
+		if err := s.mkcolParents(); err != nil {
+			return err
+		}
+		if resp, err = s.do(http.MethodPut, fileURL, data, headers); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("远程配置刚刚被其他人更新，请重新运行 'gssh push'")
+	}
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		// 服务端没有在 PUT 响应中返回 ETag 时，通过 HEAD 获取新版本
+		if etag, _, err = s.head(fileURL); err != nil {
+			return err
+		}
+	}
+	s.config.ETag = etag
+	return nil
+}
+
+// lockRequest LOCK 请求体：独占写锁，owner 记录推送者
+type lockRequest struct {
+	XMLName   xml.Name `xml:"D:lockinfo"`
+	Namespace string   `xml:"xmlns:D,attr"`
+	Exclusive struct{} `xml:"D:lockscope>D:exclusive"`
+	Write     struct{} `xml:"D:locktype>D:write"`
+	Owner     string   `xml:"D:owner>D:href"`
+}
+
+// lock 对远程配置文件加写锁，返回锁令牌（形如 <urn:uuid:...>）
+// 服务端不支持 LOCK 时返回空令牌；上级目录不存在时先创建再加锁
+func (s *WebDAVSync) lock(fileURL string) (string, error) {
+	if s.config.ETag == "" {
+		// 从未同步过时远程目录可能还不存在，部分服务端（如 golang.org/x/net/webdav）
+		// 对上级目录不存在的 LOCK 返回 500 而不是 409，所以先创建目录
+		if err := s.mkcolParents(); err != nil {
+			return "", err
+		}
+	}
+
+	name, host := syncIdentity()
+	body, err := xml.Marshal(lockRequest{Namespace: "DAV:", Owner: "gssh " + name + "@" + host})
+	if err != nil {
+		return "", fmt.Errorf("生成 LOCK 请求失败: %w", err)
+	}
+	body = append([]byte(xml.Header), body...)
+	headers := map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "0",
+		"Timeout":      webdavLockTimeout,
+	}
+
+	resp, err := s.do("LOCK", fileURL, body, headers)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusConflict {
+		resp.Body.Close()
+		if err := s.mkcolParents(); err != nil {
+			return "", err
+		}
+		if resp, err = s.do("LOCK", fileURL, body, headers); err != nil {
+			return "", err
+		}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return "", nil
+	case http.StatusLocked:
+		return "", fmt.Errorf("远程配置正在被其他客户端推送（已加锁），请稍后重试")
+	}
+	if err := checkResponse(resp); err != nil {
+		return "", fmt.Errorf("对远程配置加锁失败: %w", err)
+	}
+
+	token := resp.Header.Get("Lock-Token")
+	if token == "" {
+		return "", fmt.Errorf("对远程配置加锁失败: 服务端没有返回 Lock-Token")
+	}
+	if !strings.HasPrefix(token, "<") {
+		token = "<" + token + ">"
+	}
+	return token, nil
+}
+
+// unlock 释放写锁，失败时锁会在超时后自动过期
+func (s *WebDAVSync) unlock(fileURL, token string) {
+	resp, err := s.do("UNLOCK", fileURL, nil, map[string]string{"Lock-Token": token})
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// checkVersion 加锁后确认远程配置仍是最后一次同步的版本
+// 从未同步过时远程只能不存在或为空（对不存在的文件加锁会创建空文件）
+func (s *WebDAVSync) checkVersion(fileURL string) error {
+	etag, size, err := s.head(fileURL)
+	if err != nil {
+		return err
+	}
+	if s.config.ETag == "" {
+		if size > 0 {
+			return fmt.Errorf("远程配置刚刚被其他人更新，请重新运行 'gssh push'")
+		}
+		return nil
+	}
+	if etag != "" && etag != s.config.ETag {
+		return fmt.Errorf("远程配置刚刚被其他人更新，请重新运行 'gssh push'")
+	}
+	return nil
+}
+
+// head 获取远程配置当前的 ETag 和大小，文件不存在时返回空 ETag 和 0
+func (s *WebDAVSync) head(fileURL string) (string, int64, error) {
+	resp, err := s.do(http.MethodHead, fileURL, nil, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", 0, nil
+	}
+	if err := checkResponse(resp); err != nil {
+		return "", 0, err
+	}
+	return resp.Header.Get("ETag"), resp.ContentLength, nil
+}
+
+// mkcolParents 逐级创建配置文件的上级目录（已存在的目录返回 405，忽略）
+func (s *WebDAVSync) mkcolParents() error {
+	segments := strings.Split(s.remotePath(), "/")
+	dir := strings.TrimRight(s.config.URL, "/")
+	for _, seg := range segments[:len(segments)-1] {
+		dir += "/" + url.PathEscape(seg)
+		resp, err := s.do("MKCOL", dir+"/", nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusMethodNotAllowed {
+			continue
+		}
+		if err := checkResponse(resp); err != nil {
+			return fmt.Errorf("创建远程目录 %s 失败: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// fileURL 返回远程配置文件的完整地址（url + webdav_path）
+func (s *WebDAVSync) fileURL() (string, error) {
+	if s.config.URL == "" {
+		return "", fmt.Errorf("WebDAV 地址未配置（在 sync 配置中设置 url）")
+	}
+	var escaped []string
+	for _, seg := range strings.Split(s.remotePath(), "/") {
+		escaped = append(escaped, url.PathEscape(seg))
+	}
+	return strings.TrimRight(s.config.URL, "/") + "/" + strings.Join(escaped, "/"), nil
+}
+
+// remotePath 返回配置文件相对于 url 的路径，默认 gssh/config.yaml
+func (s *WebDAVSync) remotePath() string {
+	path := strings.Trim(s.config.WebDAVPath, "/")
+	if path == "" {
+		return defaultWebDAVPath
+	}
+	return path
+}
+
+// do 发送带认证信息的 WebDAV 请求
+func (s *WebDAVSync) do(method, target string, body []byte, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("无效的 WebDAV 地址 %s: %w", target, err)
+	}
+	if err := setAuth(req, s.config, s.quiet); err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 %s 失败: %w", target, err)
+	}
+	return resp, nil
+}
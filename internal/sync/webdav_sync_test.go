@@ -0,0 +1,188 @@
+package sync
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	gosync "sync"
+	"testing"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"golang.org/x/net/webdav"
+)
+
+// webdavServer 基于内存文件系统的 WebDAV 服务端，记录收到的请求方法
+type webdavServer struct {
+	*httptest.Server
+	mu      gosync.Mutex
+	methods []string
+	failPut bool // 为 true 时 PUT 返回 500，模拟写入失败
+}
+
+func newWebDAVServer(t *testing.T) *webdavServer {
+	t.Helper()
+	s := &webdavServer{}
+	handler := &webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.methods = append(s.methods, r.Method)
+		failPut := s.failPut
+		s.mu.Unlock()
+		if failPut && r.Method == http.MethodPut {
+			http.Error(w, "disk full", http.StatusInternalServerError)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// called 返回收到的请求中 method 出现的次数，并清空记录
+func (s *webdavServer) called(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, m := range s.methods {
+		if m == method {
+			n++
+		}
+	}
+	s.methods = nil
+	return n
+}
+
+func newTestWebDAVSync(url string) *WebDAVSync {
+	return NewWebDAVSync(&config.SyncConfig{Type: "webdav", URL: url, WebDAVPath: "team/ops/config.yaml"})
+}
+
+func testConfig(names ...string) *config.Config {
+	cfg := &config.Config{Version: config.CurrentVersion}
+	for _, name := range names {
+		cfg.Servers = append(cfg.Servers, config.Server{Name: name, Hostname: name + ".example.com"})
+	}
+	return cfg
+}
+
+func serverNames(cfg *config.Config) []string {
+	var names []string
+	for _, s := range cfg.Servers {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestWebDAVFirstPushCreatesParents(t *testing.T) {
+	srv := newWebDAVServer(t)
+	s := newTestWebDAVSync(srv.URL)
+
+	if err := s.Push(testConfig("web1")); err != nil {
+		t.Fatalf("首次推送失败: %v", err)
+	}
+	if n := srv.called("MKCOL"); n != 2 {
+		t.Errorf("MKCOL 次数 = %d，期望 2（team、team/ops）", n)
+	}
+	if s.config.ETag == "" {
+		t.Error("推送后没有记录 ETag")
+	}
+
+	// 目录已存在时不再创建
+	if err := s.Push(testConfig("web1", "web2")); err != nil {
+		t.Fatalf("再次推送失败: %v", err)
+	}
+	if n := srv.called("MKCOL"); n != 0 {
+		t.Errorf("目录已存在时 MKCOL 次数 = %d，期望 0", n)
+	}
+}
+
+func TestWebDAVPull(t *testing.T) {
+	srv := newWebDAVServer(t)
+
+	if _, err := newTestWebDAVSync(srv.URL).Pull(); !errors.Is(err, ErrRemoteNotFound) {
+		t.Fatalf("远程不存在时 Pull 错误 = %v，期望 ErrRemoteNotFound", err)
+	}
+
+	pusher := newTestWebDAVSync(srv.URL)
+	if err := pusher.Push(testConfig("web1", "db1")); err != nil {
+		t.Fatalf("推送失败: %v", err)
+	}
+
+	puller := newTestWebDAVSync(srv.URL)
+	cfg, err := puller.Pull()
+	if err != nil {
+		t.Fatalf("拉取失败: %v", err)
+	}
+	if got, want := serverNames(cfg), []string{"web1", "db1"}; !slices.Equal(got, want) {
+		t.Errorf("拉取到的服务器 = %v，期望 %v", got, want)
+	}
+	if puller.config.ETag != pusher.config.ETag {
+		t.Errorf("拉取的 ETag = %q，期望与推送后一致 %q", puller.config.ETag, pusher.config.ETag)
+	}
+}
+
+func TestWebDAVPushConflict(t *testing.T) {
+	srv := newWebDAVServer(t)
+	a := newTestWebDAVSync(srv.URL)
+	b := newTestWebDAVSync(srv.URL)
+
+	if err := a.Push(testConfig("web1")); err != nil {
+		t.Fatalf("A 推送失败: %v", err)
+	}
+	if _, err := b.Pull(); err != nil {
+		t.Fatalf("B 拉取失败: %v", err)
+	}
+	if err := a.Push(testConfig("web1", "web2")); err != nil {
+		t.Fatalf("A 再次推送失败: %v", err)
+	}
+	srv.called("UNLOCK")
+
+	// B 的 ETag 已过期，推送必须失败且不能覆盖 A 的修改
+	err := b.Push(testConfig("db1"))
+	if err == nil || !strings.Contains(err.Error(), "刚刚被其他人更新") {
+		t.Fatalf("ETag 不一致时推送错误 = %v，期望提示远程已更新", err)
+	}
+	if n := srv.called("UNLOCK"); n != 1 {
+		t.Errorf("冲突后 UNLOCK 次数 = %d，期望 1", n)
+	}
+
+	cfg, err := newTestWebDAVSync(srv.URL).Pull()
+	if err != nil {
+		t.Fatalf("拉取失败: %v", err)
+	}
+	if got, want := serverNames(cfg), []string{"web1", "web2"}; !slices.Equal(got, want) {
+		t.Errorf("冲突后远程服务器 = %v，期望 %v", got, want)
+	}
+
+	// 锁已释放，A 可以继续推送
+	if err := a.Push(testConfig("web1", "web2", "web3")); err != nil {
+		t.Fatalf("冲突后 A 推送失败（锁未释放？）: %v", err)
+	}
+}
+
+func TestWebDAVPushReleasesLockOnError(t *testing.T) {
+	srv := newWebDAVServer(t)
+	s := newTestWebDAVSync(srv.URL)
+	if err := s.Push(testConfig("web1")); err != nil {
+		t.Fatalf("推送失败: %v", err)
+	}
+	srv.called("UNLOCK")
+
+	srv.mu.Lock()
+	srv.failPut = true
+	srv.mu.Unlock()
+	if err := s.Push(testConfig("web1", "web2")); err == nil {
+		t.Fatal("PUT 失败时推送应返回错误")
+	}
+	if n := srv.called("UNLOCK"); n != 1 {
+		t.Errorf("写入失败后 UNLOCK 次数 = %d，期望 1", n)
+	}
+
+	srv.mu.Lock()
+	srv.failPut = false
+	srv.mu.Unlock()
+	if err := s.Push(testConfig("web1", "web2")); err != nil {
+		t.Fatalf("写入失败后再次推送失败（锁未释放？）: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SOCKS5 协议常量（RFC 1928）
+const (
+	socksVersion5      = 0x05
+	socksMethodNoAuth  = 0x00
+	socksNoAcceptable  = 0xff
+	socksCmdConnect    = 0x01
+	socksAtypIPv4      = 0x01
+	socksAtypDomain    = 0x03
+	socksAtypIPv6      = 0x04
+	socksRepSucceeded  = 0x00
+	socksRepFailure    = 0x01
+	socksRepCmdUnsupp  = 0x07
+	socksRepAtypUnsupp = 0x08
+)
+
+// handleSocks 处理一个 SOCKS5 连接（dynamic），只支持无认证的 CONNECT
+func (t *Tunnel) handleSocks(f *forward, client *ssh.Client, conn net.Conn) {
+	target, err := socksHandshake(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	remote, err := client.Dial("tcp", target)
+	if err != nil {
+		socksReply(conn, socksRepFailure)
+		conn.Close()
+		return
+	}
+	if err := socksReply(conn, socksRepSucceeded); err != nil {
+		conn.Close()
+		remote.Close()
+		return
+	}
+
+	t.pipe(f, conn, remote)
+}
+
+// socksHandshake 完成 SOCKS5 协商并返回客户端请求的目标地址
+func socksHandshake(conn net.Conn) (string, error) {
+	// 版本与认证方式
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	if header[0] != socksVersion5 {
+		return "", fmt.Errorf("不支持的 SOCKS 版本: %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+	noAuth := false
+	for _, m := range methods {
+		if m == socksMethodNoAuth {
+			noAuth = true
+			break
+		}
+	}
+	if !noAuth {
+		conn.Write([]byte{socksVersion5, socksNoAcceptable})
+		return "", fmt.Errorf("客户端不支持无认证方式")
+	}
+	if _, err := conn.Write([]byte{socksVersion5, socksMethodNoAuth}); err != nil {
+		return "", err
+	}
+
+	// 请求
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return "", err
+	}
+	if req[1] != socksCmdConnect {
+		socksReply(conn, socksRepCmdUnsupp)
+		return "", fmt.Errorf("不支持的 SOCKS 命令: %d", req[1])
+	}
+
+	var host string
+	switch req[3] {
+	case socksAtypIPv4:
+		addr := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(conn, addr); err != nil {
+			return "", err
+		}
+		host = net.IP(addr).String()
+	case socksAtypIPv6:
+		addr := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(conn, addr); err != nil {
+			return "", err
+		}
+		host = net.IP(addr).String()
+	case socksAtypDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return "", err
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", err
+		}
+		host = string(domain)
+	default:
+		socksReply(conn, socksRepAtypUnsupp)
+		return "", fmt.Errorf("不支持的地址类型: %d", req[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// socksReply 发送 SOCKS5 应答（绑定地址固定为 0.0.0.0:0）
+func socksReply(conn net.Conn, rep byte) error {
+	_, err := conn.Write([]byte{socksVersion5, rep, 0x00, socksAtypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -0,0 +1,353 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// 转发状态
+const (
+	stateConnecting = "连接中"
+	stateActive     = "已建立"
+	stateRetrying   = "重连中"
+	stateError      = "失败"
+)
+
+const (
+	keepaliveInterval = 15 * time.Second
+	maxRetryDelay     = 30 * time.Second
+)
+
+// Tunnel 一组基于同一 SSH 连接的端口转发，连接断开后自动重连
+type Tunnel struct {
+	name     string
+	dial     func() (*ssh.Client, error)
+	forwards []*forward
+	out      io.Writer
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// forward 单个端口转发及其运行状态
+type forward struct {
+	config.Forward
+	listener net.Listener // local/dynamic 的本地监听，重连期间保持不变
+
+	state  string
+	detail string
+	conns  atomic.Int32
+}
+
+// New 创建端口转发实例
+// dial 用于建立（以及断线后重新建立）SSH 连接
+func New(name string, forwards []config.Forward, dial func() (*ssh.Client, error), out io.Writer) (*Tunnel, error) {
+	if len(forwards) == 0 {
+		return nil, fmt.Errorf("没有可用的端口转发配置")
+	}
+
+	t := &Tunnel{name: name, dial: dial, out: out}
+	for _, f := range forwards {
+		if err := validateForward(f); err != nil {
+			return nil, err
+		}
+		f.Listen = normalizeListen(f.Listen)
+		t.forwards = append(t.forwards, &forward{Forward: f, state: stateConnecting})
+	}
+	return t, nil
+}
+
+// Run 建立所有转发并保持运行，直到 ctx 被取消
+func (t *Tunnel) Run(ctx context.Context) error {
+	// 本地监听只建立一次，重连期间端口保持占用
+	for _, f := range t.forwards {
+		if f.Type == "remote" {
+			continue
+		}
+		ln, err := net.Listen("tcp", f.Listen)
+		if err != nil {
+			t.closeListeners()
+			return fmt.Errorf("转发 %s 监听 %s 失败: %w", f.Name, f.Listen, err)
+		}
+		f.listener = ln
+		go t.acceptLocal(f)
+	}
+	defer t.closeListeners()
+
+	delay := time.Second
+	for {
+		client, err := t.dial()
+		if err != nil {
+			t.setAll(stateRetrying, fmt.Sprintf("%v，%s 后重试", err, delay))
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, maxRetryDelay)
+			continue
+		}
+		delay = time.Second
+
+		t.mu.Lock()
+		t.client = client
+		t.mu.Unlock()
+
+		// 先建立所有远程监听，再统一更新状态，避免重复打印
+		remoteErrs := make(map[*forward]error)
+		for _, f := range t.forwards {
+			if f.Type == "remote" {
+				if err := t.startRemote(client, f); err != nil {
+					remoteErrs[f] = err
+				}
+			}
+		}
+		t.mu.Lock()
+		for _, f := range t.forwards {
+			if err, ok := remoteErrs[f]; ok {
+				f.state, f.detail = stateError, err.Error()
+			} else {
+				f.state, f.detail = stateActive, ""
+			}
+		}
+		t.mu.Unlock()
+		t.printStatus()
+
+		done := make(chan struct{})
+		go func() {
+			client.Wait()
+			close(done)
+		}()
+		go keepalive(client, done)
+
+		select {
+		case <-ctx.Done():
+			client.Close()
+			return nil
+		case <-done:
+		}
+
+		t.mu.Lock()
+		t.client = nil
+		t.mu.Unlock()
+		t.setAll(stateRetrying, "SSH 连接已断开")
+	}
+}
+
+// currentClient 获取当前可用的 SSH 连接，断线期间返回 nil
+func (t *Tunnel) currentClient() *ssh.Client {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.client
+}
+
+// acceptLocal 接收本地连接（local / dynamic）
+func (t *Tunnel) acceptLocal(f *forward) {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		client := t.currentClient()
+		if client == nil {
+			// 断线重连期间直接拒绝新连接
+			conn.Close()
+			continue
+		}
+
+		go func() {
+			if f.Type == "dynamic" {
+				t.handleSocks(f, client, conn)
+				return
+			}
+			remote, err := client.Dial("tcp", f.Target)
+			if err != nil {
+				conn.Close()
+				t.setState(f, stateActive, fmt.Sprintf("连接 %s 失败: %v", f.Target, err))
+				return
+			}
+			t.pipe(f, conn, remote)
+		}()
+	}
+}
+
+// startRemote 在远程服务器上监听并把连接转发到本地目标（remote）
+func (t *Tunnel) startRemote(client *ssh.Client, f *forward) error {
+	ln, err := client.Listen("tcp", f.Listen)
+	if err != nil {
+		return fmt.Errorf("远程监听 %s 失败: %v", f.Listen, err)
+	}
+
+	go func() {
+		defer ln.Close()
+		for {
+			remote, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				local, err := net.Dial("tcp", f.Target)
+				if err != nil {
+					remote.Close()
+					t.setState(f, stateActive, fmt.Sprintf("连接 %s 失败: %v", f.Target, err))
+					return
+				}
+				t.pipe(f, remote, local)
+			}()
+		}
+	}()
+	return nil
+}
+
+// pipe 双向转发数据，任一方向结束后关闭两端
+func (t *Tunnel) pipe(f *forward, a, b net.Conn) {
+	f.conns.Add(1)
+	defer f.conns.Add(-1)
+
+	var once sync.Once
+	closeBoth := func() {
+		a.Close()
+		b.Close()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(a, b)
+		once.Do(closeBoth)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(b, a)
+		once.Do(closeBoth)
+	}()
+	wg.Wait()
+}
+
+// setState 更新单个转发的状态，状态变化时打印
+func (t *Tunnel) setState(f *forward, state, detail string) {
+	t.mu.Lock()
+	changed := f.state != state || f.detail != detail
+	f.state = state
+	f.detail = detail
+	t.mu.Unlock()
+
+	if changed {
+		t.printStatus()
+	}
+}
+
+// setAll 更新所有转发的状态，状态变化时打印
+func (t *Tunnel) setAll(state, detail string) {
+	t.mu.Lock()
+	changed := false
+	for _, f := range t.forwards {
+		if f.state != state || f.detail != detail {
+			changed = true
+		}
+		f.state = state
+		f.detail = detail
+	}
+	t.mu.Unlock()
+
+	if changed {
+		t.printStatus()
+	}
+}
+
+// printStatus 打印所有转发的当前状态
+func (t *Tunnel) printStatus() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s\n", time.Now().Format("15:04:05"), t.name)
+	for _, f := range t.forwards {
+		route := f.Listen
+		if f.Type != "dynamic" {
+			route += " -> " + f.Target
+		}
+		fmt.Fprintf(&b, "  %-12s %-8s %-40s %-6s 连接数 %d", f.Name, f.Type, route, f.state, f.conns.Load())
+		if f.detail != "" {
+			fmt.Fprintf(&b, "  (%s)", f.detail)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprint(t.out, b.String())
+}
+
+// closeListeners 关闭所有本地监听
+func (t *Tunnel) closeListeners() {
+	for _, f := range t.forwards {
+		if f.listener != nil {
+			f.listener.Close()
+		}
+	}
+}
+
+// keepalive 定期发送心跳，无响应时主动断开连接以触发重连
+func keepalive(client *ssh.Client, done <-chan struct{}) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			replied := make(chan error, 1)
+			go func() {
+				_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+				replied <- err
+			}()
+			select {
+			case err := <-replied:
+				if err != nil {
+					client.Close()
+					return
+				}
+			case <-time.After(keepaliveInterval):
+				client.Close()
+				return
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+// validateForward 检查转发配置是否完整
+func validateForward(f config.Forward) error {
+	switch f.Type {
+	case "local", "remote":
+		if f.Target == "" {
+			return fmt.Errorf("转发 %s 缺少 target", f.Name)
+		}
+	case "dynamic":
+	default:
+		return fmt.Errorf("转发 %s 的类型 '%s' 不支持（可选 local/remote/dynamic）", f.Name, f.Type)
+	}
+	if f.Listen == "" {
+		return fmt.Errorf("转发 %s 缺少 listen", f.Name)
+	}
+	return nil
+}
+
+// normalizeListen 只写端口时默认监听 127.0.0.1
+func normalizeListen(listen string) string {
+	if !strings.Contains(listen, ":") {
+		return net.JoinHostPort("127.0.0.1", listen)
+	}
+	return listen
+}
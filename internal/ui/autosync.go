@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/sync"
+	"github.com/fijdemon/gssh/internal/util"
+)
+
+// 自动同步状态
+const (
+	syncSyncing = "syncing"
+	syncOK      = "ok"
+	syncFailed  = "failed"
+)
+
+// autoSyncMsg 后台自动同步结束
+type autoSyncMsg struct {
+	err error
+}
+
+// startAutoSync 开启自动同步时在后台执行 run（AutoPull / AutoPush），结束后发送 autoSyncMsg
+func (m *Model) startAutoSync(run func() error) tea.Cmd {
+	if !sync.AutoEnabled(m.config) || m.syncState == syncSyncing {
+		return nil
+	}
+
+	done := make(chan struct{})
+	m.syncState = syncSyncing
+	m.syncErr = ""
+	m.syncDone = done
+	return func() tea.Msg {
+		defer close(done)
+		return autoSyncMsg{err: run()}
+	}
+}
+
+// finishAutoSync 记录同步结果，成功时重新加载同步后的服务器列表
+func (m *Model) finishAutoSync(msg autoSyncMsg) {
+	m.syncTime = time.Now()
+	if msg.err != nil {
+		m.syncState = syncFailed
+		// 只显示第一行，详细信息可运行 gssh pull 查看
+		m.syncErr, _, _ = strings.Cut(msg.err.Error(), "\n")
+		return
+	}
+
+	m.syncState = syncOK
+	if cfg, err := config.Load(); err == nil {
+		m.config = cfg
+		m.servers = cfg.Servers
+		m.refreshList()
+	}
+}
+
+// waitAutoSync 退出界面后等待正在进行的同步结束，避免进程退出时中断写入配置
+func (m Model) waitAutoSync() {
+	if m.syncState != syncSyncing || m.syncDone == nil {
+		return
+	}
+	select {
+	case <-m.syncDone:
+	case <-time.After(sync.AutoSyncTimeout):
+	}
+}
+
+// syncStatusView 渲染标题栏右侧的同步状态，width 为可用的显示宽度
+func (m Model) syncStatusView(width int) string {
+	var text, color string
+	switch m.syncState {
+	case syncSyncing:
+		text, color = "⟳ 同步中，暂不能修改", "214"
+	case syncOK:
+		text, color = "✓ 已同步 "+m.syncTime.Format("15:04"), "42"
+	case syncFailed:
+		text, color = "✗ 同步失败: "+m.syncErr, "196"
+	default:
+		return ""
+	}
+
+	if width <= 0 {
+		return ""
+	}
+	if util.DisplayWidth(text) > width {
+		runes := []rune(text)
+		for len(runes) > 0 && util.DisplayWidth(string(runes))+1 > width {
+			runes = runes[:len(runes)-1]
+		}
+		text = string(runes) + "…"
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(text)
+}
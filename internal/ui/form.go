@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -12,10 +13,28 @@ import (
 	"github.com/fijdemon/gssh/internal/config"
 )
 
+// 表单字段的索引
+const (
+	fieldName = iota
+	fieldHostname
+	fieldTemplate
+	fieldUser
+	fieldPort
+	fieldDescription
+	fieldGroup
+	fieldTags
+	fieldAuthType
+	fieldPassword
+	fieldIdentityFile
+	fieldJump
+	fieldCount
+)
+
 // FormModel 表单模型
 type FormModel struct {
 	inputs        []textinput.Model
-	currentIndex  int // 当前正在输入的字段索引
+	currentIndex  int            // 当前正在输入的字段索引
+	config        *config.Config // 用于检查模板、显示继承的值
 	width         int
 	height        int
 	isEdit        bool
@@ -23,6 +42,7 @@ type FormModel struct {
 	onSave        func(config.Server) error
 	onCancel      func()
 	quitting      bool     // 标记是否正在退出
+	saved         bool     // 退出前是否保存成功
 	fieldLabels   []string // 字段标签
 }
 
@@ -62,11 +82,13 @@ func (m FormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "enter", "down":
-			// 验证当前字段（如果是必填字段）
-			if m.isRequiredField(m.currentIndex) && m.inputs[m.currentIndex].Value() == "" {
-				// 必填字段为空，不继续
+			// 验证当前字段（必填字段为空或模板不存在时不继续）
+			if !m.fieldValid(m.currentIndex) {
 				return m, nil
 			}
+			if m.currentIndex == fieldTemplate {
+				m.showInherited()
+			}
 
 			// 如果是最后一个字段，保存
 			if m.currentIndex == len(m.inputs)-1 {
@@ -89,72 +111,119 @@ func (m FormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-// isRequiredField 检查字段是否为必填
-func (m FormModel) isRequiredField(index int) bool {
-	// 名称、主机地址、用户名为必填字段
-	return index == 0 || index == 1 || index == 2
+// fieldValid 检查字段的输入：名称、主机地址必填；用户名在 defaults / 模板都没有设置时必填；模板必须存在
+func (m FormModel) fieldValid(index int) bool {
+	value := strings.TrimSpace(m.inputs[index].Value())
+	switch index {
+	case fieldName, fieldHostname:
+		return value != ""
+	case fieldUser:
+		return value != "" || m.inherited().User != ""
+	case fieldTemplate:
+		if value == "" {
+			return true
+		}
+		_, err := m.config.GetTemplate(value)
+		return err == nil
+	}
+	return true
+}
+
+// inherited 返回按当前填写的模板继承的值
+func (m FormModel) inherited() config.ServerTemplate {
+	return m.config.Inherited(strings.TrimSpace(m.inputs[fieldTemplate].Value()))
 }
 
-// saveServer 保存服务器
-func (m FormModel) saveServer() (tea.Model, tea.Cmd) {
-	// 验证必填字段
-	if m.inputs[0].Value() == "" {
-		return m, nil // 名称不能为空
-	}
-	if m.inputs[1].Value() == "" {
-		return m, nil // 主机地址不能为空
+// showInherited 在可以继承的字段中提示留空时使用的值
+func (m *FormModel) showInherited() {
+	t := m.inherited()
+	port := ""
+	if t.Port != 0 {
+		port = strconv.Itoa(t.Port)
 	}
-	if m.inputs[2].Value() == "" {
-		return m, nil // 用户名不能为空
+	authType := t.Auth.Type
+	if authType == "" {
+		authType = "auto"
 	}
-
-	// 解析端口
-	port := 22
-	if m.inputs[3].Value() != "" {
-		if p, err := strconv.Atoi(m.inputs[3].Value()); err == nil {
-			port = p
+	hint := func(index int, value, fallback string) {
+		if value != "" {
+			m.inputs[index].Placeholder = "留空使用 " + value
+		} else {
+			m.inputs[index].Placeholder = fallback
 		}
 	}
+	hint(fieldUser, t.User, "例如: root")
+	hint(fieldPort, port, "22")
+	hint(fieldGroup, t.Group, "例如: production")
+	hint(fieldTags, strings.Join(t.Tags, ","), "例如: web,nginx,production")
+	hint(fieldAuthType, authType, "auto")
+	hint(fieldIdentityFile, t.Auth.IdentityFile, "留空只使用 ssh-agent")
+	hint(fieldJump, strings.Join(t.Jump, ","), "例如: bastion（留空表示直连）")
+
+	// password_cmd / passphrase_cmd 不在表单中编辑，保存时保留
+	if cmd := m.passwordCmd(); cmd != "" {
+		m.inputs[fieldPassword].Placeholder = "留空则使用 password_cmd: " + cmd
+	} else {
+		m.inputs[fieldPassword].Placeholder = "留空则不存储密码"
+	}
+	if m.passphraseCmd() != "" {
+		m.inputs[fieldIdentityFile].Placeholder += "（密钥密码使用 passphrase_cmd）"
+	}
+}
 
-	// 解析标签
-	tags := []string{}
-	if m.inputs[6].Value() != "" {
-		tags = strings.Split(m.inputs[6].Value(), ",")
-		for i := range tags {
-			tags[i] = strings.TrimSpace(tags[i])
-		}
+// passwordCmd 返回保存后使用的 password_cmd（服务器自己设置的或继承的）
+func (m FormModel) passwordCmd() string {
+	if m.editingServer != nil && m.editingServer.Auth.PasswordCmd != "" {
+		return m.editingServer.Auth.PasswordCmd
 	}
+	return m.inherited().Auth.PasswordCmd
+}
 
-	// 创建服务器配置
-	server := config.Server{
-		Name:        strings.TrimSpace(m.inputs[0].Value()),
-		Hostname:    strings.TrimSpace(m.inputs[1].Value()),
-		User:        strings.TrimSpace(m.inputs[2].Value()),
-		Port:        port,
-		Description: strings.TrimSpace(m.inputs[4].Value()),
-		Group:       strings.TrimSpace(m.inputs[5].Value()),
-		Tags:        tags,
-		Auth: config.AuthConfig{
-			Type:         strings.TrimSpace(m.inputs[7].Value()),
-			Password:     strings.TrimSpace(m.inputs[8].Value()),
-			IdentityFile: strings.TrimSpace(m.inputs[9].Value()),
-		},
+// passphraseCmd 返回保存后使用的 passphrase_cmd（服务器自己设置的或继承的）
+func (m FormModel) passphraseCmd() string {
+	if m.editingServer != nil && m.editingServer.Auth.PassphraseCmd != "" {
+		return m.editingServer.Auth.PassphraseCmd
 	}
+	return m.inherited().Auth.PassphraseCmd
+}
 
-	// 设置默认值
-	if server.Auth.Type == "" {
-		server.Auth.Type = "auto"
+// saveServer 保存服务器
+func (m FormModel) saveServer() (tea.Model, tea.Cmd) {
+	// 验证必填字段
+	for i := range m.inputs {
+		if !m.fieldValid(i) {
+			return m, nil
+		}
 	}
-	if server.Auth.IdentityFile == "" {
-		server.Auth.IdentityFile = "~/.ssh/id_rsa"
+
+	// 解析端口，留空（或无效）时使用继承的端口，都没有时为 22
+	port := 0
+	if p, err := strconv.Atoi(strings.TrimSpace(m.inputs[fieldPort].Value())); err == nil && p > 0 {
+		port = p
 	}
 
+	// 编辑时在原配置上修改，保留表单中没有的字段（端口转发等）
+	// 留空的字段保持为空，保存时使用 defaults / 模板中的值
+	var server config.Server
 	if m.isEdit && m.editingServer != nil {
-		// 编辑模式：保留创建时间
-		server.CreatedAt = m.editingServer.CreatedAt
-		server.LastUsed = m.editingServer.LastUsed
-	} else {
-		// 新建模式：设置创建时间
+		server = *m.editingServer
+		server.Forwards = slices.Clone(m.editingServer.Forwards)
+	}
+	server.Name = strings.TrimSpace(m.inputs[fieldName].Value())
+	server.Hostname = strings.TrimSpace(m.inputs[fieldHostname].Value())
+	server.Template = strings.TrimSpace(m.inputs[fieldTemplate].Value())
+	server.User = strings.TrimSpace(m.inputs[fieldUser].Value())
+	server.Port = port
+	server.Description = strings.TrimSpace(m.inputs[fieldDescription].Value())
+	server.Group = strings.TrimSpace(m.inputs[fieldGroup].Value())
+	server.Tags = splitList(m.inputs[fieldTags].Value())
+	server.Auth.Type = strings.TrimSpace(m.inputs[fieldAuthType].Value())
+	server.Auth.Password = strings.TrimSpace(m.inputs[fieldPassword].Value())
+	server.Auth.IdentityFile = strings.TrimSpace(m.inputs[fieldIdentityFile].Value())
+	server.Jump = splitList(m.inputs[fieldJump].Value())
+
+	if !m.isEdit {
+		// 新建模式：设置创建时间（编辑模式保留原来的创建时间和最后使用时间）
 		server.CreatedAt = time.Now().Format(time.RFC3339)
 	}
 
@@ -168,10 +237,22 @@ func (m FormModel) saveServer() (tea.Model, tea.Cmd) {
 	}
 
 	// 保存成功，退出表单
+	m.saved = true
 	m.quitting = true
 	return m, nil
 }
 
+// splitList 解析逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // View 渲染表单
 func (m FormModel) View() string {
 	// 即使 width 为 0 也显示表单，使用默认宽度
@@ -223,10 +304,12 @@ func (m FormModel) View() string {
 }
 
 // NewFormModel 创建表单模型
-func NewFormModel(editingServer *config.Server, onSave func(config.Server) error, onCancel func()) FormModel {
+// 编辑时 editingServer 应为服务器自己设置的字段（config.ServerOverrides），继承的值不填入表单
+func NewFormModel(cfg *config.Config, editingServer *config.Server, onSave func(config.Server) error, onCancel func()) FormModel {
 	m := FormModel{
-		inputs:        make([]textinput.Model, 10),
+		inputs:        make([]textinput.Model, fieldCount),
 		currentIndex:  0,
+		config:        cfg,
 		isEdit:        editingServer != nil,
 		editingServer: editingServer,
 		onSave:        onSave,
@@ -234,7 +317,8 @@ func NewFormModel(editingServer *config.Server, onSave func(config.Server) error
 		fieldLabels: []string{
 			"名称 *",
 			"主机地址 *",
-			"用户名 *",
+			"模板",
+			"用户名",
 			"端口",
 			"描述",
 			"分组",
@@ -242,66 +326,55 @@ func NewFormModel(editingServer *config.Server, onSave func(config.Server) error
 			"认证类型 (auto/key/password)",
 			"密码",
 			"密钥路径",
+			"跳板机（逗号分隔，按连接顺序）",
 		},
 	}
 
 	// 初始化输入框
-	inputs := []textinput.Model{
-		textinput.New(), // 名称
-		textinput.New(), // 主机地址
-		textinput.New(), // 用户名
-		textinput.New(), // 端口
-		textinput.New(), // 描述
-		textinput.New(), // 分组
-		textinput.New(), // 标签
-		textinput.New(), // 认证类型
-		textinput.New(), // 密码
-		textinput.New(), // 密钥路径
+	inputs := make([]textinput.Model, fieldCount)
+	for i := range inputs {
+		inputs[i] = textinput.New()
 	}
 
 	// 设置输入框属性
-	inputs[0].Placeholder = "例如: prod-web"
-	inputs[0].Focus()
-	inputs[0].CharLimit = 50
-
-	inputs[1].Placeholder = "例如: 192.168.1.100"
-	inputs[1].CharLimit = 100
+	inputs[fieldName].Placeholder = "例如: prod-web"
+	inputs[fieldName].CharLimit = 50
 
-	inputs[2].Placeholder = "例如: root"
-	inputs[2].CharLimit = 50
+	inputs[fieldHostname].Placeholder = "例如: 192.168.1.100"
+	inputs[fieldHostname].CharLimit = 100
 
-	inputs[3].Placeholder = "22"
-	inputs[3].CharLimit = 5
-
-	inputs[4].Placeholder = "例如: 生产环境Web服务器"
-	inputs[4].CharLimit = 100
-
-	inputs[5].Placeholder = "例如: production"
-	inputs[5].CharLimit = 50
-
-	inputs[6].Placeholder = "例如: web,nginx,production"
-	inputs[6].CharLimit = 200
-
-	inputs[7].Placeholder = "auto"
-	inputs[7].CharLimit = 20
-
-	inputs[8].Placeholder = "留空则不存储密码"
-
-	inputs[9].Placeholder = "~/.ssh/id_rsa"
-	inputs[9].CharLimit = 200
-
-	// 如果是编辑模式，填充现有值
+	inputs[fieldTemplate].Placeholder = "留空不使用模板"
+	if names := templateNames(cfg); len(names) > 0 {
+		inputs[fieldTemplate].Placeholder = "可选: " + strings.Join(names, ", ") + "（留空不使用模板）"
+	}
+	inputs[fieldTemplate].CharLimit = 50
+
+	inputs[fieldUser].CharLimit = 50
+	inputs[fieldPort].CharLimit = 5
+	inputs[fieldDescription].Placeholder = "例如: 生产环境Web服务器"
+	inputs[fieldDescription].CharLimit = 100
+	inputs[fieldGroup].CharLimit = 50
+	inputs[fieldTags].CharLimit = 200
+	inputs[fieldAuthType].CharLimit = 20
+	inputs[fieldIdentityFile].CharLimit = 200
+	inputs[fieldJump].CharLimit = 200
+
+	// 如果是编辑模式，填充服务器自己设置的值（留空的字段继承 defaults / 模板）
 	if editingServer != nil {
-		inputs[0].SetValue(editingServer.Name)
-		inputs[1].SetValue(editingServer.Hostname)
-		inputs[2].SetValue(editingServer.User)
-		inputs[3].SetValue(fmt.Sprintf("%d", editingServer.Port))
-		inputs[4].SetValue(editingServer.Description)
-		inputs[5].SetValue(editingServer.Group)
-		inputs[6].SetValue(strings.Join(editingServer.Tags, ","))
-		inputs[7].SetValue(editingServer.Auth.Type)
-		inputs[8].SetValue(editingServer.Auth.Password)
-		inputs[9].SetValue(editingServer.Auth.IdentityFile)
+		inputs[fieldName].SetValue(editingServer.Name)
+		inputs[fieldHostname].SetValue(editingServer.Hostname)
+		inputs[fieldTemplate].SetValue(editingServer.Template)
+		inputs[fieldUser].SetValue(editingServer.User)
+		if editingServer.Port != 0 {
+			inputs[fieldPort].SetValue(strconv.Itoa(editingServer.Port))
+		}
+		inputs[fieldDescription].SetValue(editingServer.Description)
+		inputs[fieldGroup].SetValue(editingServer.Group)
+		inputs[fieldTags].SetValue(strings.Join(editingServer.Tags, ","))
+		inputs[fieldAuthType].SetValue(editingServer.Auth.Type)
+		inputs[fieldPassword].SetValue(editingServer.Auth.Password)
+		inputs[fieldIdentityFile].SetValue(editingServer.Auth.IdentityFile)
+		inputs[fieldJump].SetValue(strings.Join(editingServer.Jump, ","))
 	}
 
 	// 设置样式
@@ -318,5 +391,16 @@ func NewFormModel(editingServer *config.Server, onSave func(config.Server) error
 	}
 
 	m.inputs = inputs
+	m.showInherited()
 	return m
 }
+
+// templateNames 返回配置中的模板名称（按名称排序）
+func templateNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Templates))
+	for name := range cfg.Templates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
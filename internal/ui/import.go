@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/sshconfig"
+)
+
+// ImportModel 从 ~/.ssh/config 导入服务器的勾选列表
+type ImportModel struct {
+	path       string
+	candidates []sshconfig.Candidate
+	selected   map[string]bool
+	cursor     int
+	width      int
+	height     int
+	warnings   []string
+	err        string // 解析或导入失败的原因
+	onImport   func(candidates []sshconfig.Candidate, selected map[string]bool) error
+	quitting   bool // 标记是否正在退出
+	imported   bool // 退出前是否导入成功
+}
+
+// NewImportModel 解析 ~/.ssh/config 并创建导入列表，默认勾选所有不冲突的主机
+func NewImportModel(cfg *config.Config, onImport func([]sshconfig.Candidate, map[string]bool) error) ImportModel {
+	m := ImportModel{
+		selected: make(map[string]bool),
+		onImport: onImport,
+	}
+
+	path, err := sshconfig.DefaultPath()
+	if err != nil {
+		m.err = err.Error()
+		return m
+	}
+	m.path = path
+
+	hosts, warnings, err := sshconfig.Parse(path)
+	m.warnings = warnings
+	if err != nil {
+		m.err = err.Error()
+		return m
+	}
+
+	m.candidates = sshconfig.Plan(cfg, hosts, "")
+	for _, c := range m.candidates {
+		// 自动生成的跳板机随依赖它的服务器一起导入，不单独勾选
+		if !c.Conflict && c.Source != "" {
+			m.selected[c.Server.Name] = true
+		}
+	}
+	return m
+}
+
+// Update 处理按键
+func (m ImportModel) Update(msg tea.Msg) (ImportModel, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "esc", "q", "ctrl+c":
+		m.quitting = true
+	case "j", "down":
+		if m.cursor < len(m.candidates)-1 {
+			m.cursor++
+		}
+	case "k", "up":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case " ", "x":
+		if m.cursor < len(m.candidates) {
+			c := m.candidates[m.cursor]
+			if !c.Conflict && c.Source != "" {
+				m.selected[c.Server.Name] = !m.selected[c.Server.Name]
+			}
+		}
+	case "a":
+		// 全选 / 全不选
+		all := true
+		for _, c := range m.candidates {
+			if !c.Conflict && c.Source != "" && !m.selected[c.Server.Name] {
+				all = false
+				break
+			}
+		}
+		for _, c := range m.candidates {
+			if !c.Conflict && c.Source != "" {
+				m.selected[c.Server.Name] = !all
+			}
+		}
+	case "enter":
+		if len(m.candidates) == 0 || m.onImport == nil {
+			m.quitting = true
+			return m, nil
+		}
+		if err := m.onImport(m.candidates, m.selected); err != nil {
+			m.err = err.Error()
+			return m, nil
+		}
+		m.imported = true
+		m.quitting = true
+	}
+	return m, nil
+}
+
+// View 渲染导入列表
+func (m ImportModel) View() string {
+	gray := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	red := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212")).Render("从 ssh_config 导入服务器"))
+	b.WriteString("\n")
+	if m.path != "" {
+		b.WriteString(gray.Render(m.path))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	if m.err != "" {
+		b.WriteString(red.Render("错误: " + m.err))
+		b.WriteString("\n\n")
+	}
+	for _, w := range m.warnings {
+		b.WriteString(gray.Render("警告: " + w))
+		b.WriteString("\n")
+	}
+
+	if len(m.candidates) == 0 && m.err == "" {
+		b.WriteString("没有可导入的主机（含通配符的 Host 会被跳过）\n")
+	}
+
+	// 只显示光标附近能放下的行
+	visible := max(m.height-10-len(m.warnings), 5)
+	start := 0
+	if m.cursor >= visible {
+		start = m.cursor - visible + 1
+	}
+	end := min(start+visible, len(m.candidates))
+
+	for i := start; i < end; i++ {
+		c := m.candidates[i]
+		check := "[ ]"
+		if m.selected[c.Server.Name] {
+			check = "[x]"
+		}
+		note := ""
+		switch {
+		case c.Conflict:
+			check = "[-]"
+			note = "  名称已存在，跳过"
+		case c.Source == "":
+			check = "[+]"
+			note = "  ProxyJump 跳板机，随依赖它的服务器导入"
+		}
+
+		line := fmt.Sprintf("%s %s  %s:%d (%s)", check, c.Server.Name, c.Server.Hostname, c.Server.GetPort(), c.Server.User)
+		if len(c.Server.Jump) > 0 {
+			line += "  via " + strings.Join(c.Server.Jump, ",")
+		}
+
+		prefix := "  "
+		if i == m.cursor {
+			prefix = "> "
+		}
+		if c.Conflict || c.Source == "" {
+			b.WriteString(gray.Render(prefix + line + note))
+		} else if i == m.cursor {
+			b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Render(prefix + line))
+		} else {
+			b.WriteString(prefix + line)
+		}
+		b.WriteString("\n")
+	}
+
+	selectedCount := 0
+	for _, v := range m.selected {
+		if v {
+			selectedCount++
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(gray.Render(fmt.Sprintf("已选择 %d 台 | 空格 勾选 | a 全选/全不选 | j/k 移动 | Enter 导入 | Esc 取消", selectedCount)))
+	b.WriteString("\n")
+	return b.String()
+}
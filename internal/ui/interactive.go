@@ -2,13 +2,17 @@ package ui
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/fijdemon/gssh/internal/config"
+	"github.com/fijdemon/gssh/internal/sshconfig"
+	"github.com/fijdemon/gssh/internal/sync"
 )
 
 // Model UI模型
@@ -29,11 +33,18 @@ type Model struct {
 	deleteConfirm     bool
 	deleteConfirmInput textinput.Model // 删除确认输入框
 	pendingServer     *config.Server   // 待连接的服务器，在退出tea后执行
+	importMode        bool
+	importer          ImportModel // 从 ~/.ssh/config 导入的勾选列表
+	syncState         string        // 自动同步状态：syncing / ok / failed，为空表示未开启自动同步
+	syncErr           string        // 同步失败的原因
+	syncTime          time.Time     // 最后一次同步结束的时间
+	syncDone          chan struct{} // 正在进行的同步结束时关闭
+	initCmd           tea.Cmd       // 启动时执行的命令（自动拉取）
 }
 
 // Init 初始化
 func (m Model) Init() tea.Cmd {
-	return nil
+	return m.initCmd
 }
 
 // enterPreSearchMode 进入预搜索模式
@@ -63,6 +74,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.importer.width = msg.Width
+		m.importer.height = msg.Height
 		if m.formMode {
 			m.form.width = msg.Width
 			m.form.height = msg.Height
@@ -82,6 +95,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case autoSyncMsg:
+		m.finishAutoSync(msg)
+		return m, nil
+
 	case tea.KeyMsg:
 		// 表单模式
 		if m.formMode {
@@ -94,6 +111,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.form.quitting {
 					m.formMode = false
 					m.refreshList()
+					if m.form.saved {
+						// 保存成功后自动推送
+						return m, m.startAutoSync(sync.AutoPush)
+					}
 					return m, nil
 				}
 			} else {
@@ -104,6 +125,20 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
+		// 导入模式
+		if m.importMode {
+			var cmd tea.Cmd
+			m.importer, cmd = m.importer.Update(msg)
+			if m.importer.quitting {
+				m.importMode = false
+				m.refreshList()
+				if m.importer.imported {
+					return m, tea.Batch(cmd, m.startAutoSync(sync.AutoPush))
+				}
+			}
+			return m, cmd
+		}
+
 		// 删除确认模式
 		if m.deleteConfirm {
 			switch msg.String() {
@@ -121,8 +156,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					serverName := item.server.Name
 					// 只有输入的名称完全匹配时才执行删除
 					if inputName == serverName {
-						m.config.DeleteServer(serverName)
-						config.Save(m.config)
+						m.updateConfig(func(cfg *config.Config) error {
+							return cfg.DeleteServer(serverName)
+						})
 						m.refreshList()
 						m.deleteConfirm = false
 						m.deleteConfirmInput.Blur()
@@ -131,6 +167,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						if len(m.list.Items()) > 0 {
 							m.list.Select(0)
 						}
+						return m, m.startAutoSync(sync.AutoPush)
 					}
 					// 如果不匹配，不清除输入，让用户重新输入
 				}
@@ -205,6 +242,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		// 自动同步进行中时不允许添加、删除、编辑和导入，避免与同步写入的配置互相覆盖
+		if m.syncState == syncSyncing {
+			switch msg.String() {
+			case "a", "d", "e", "i":
+				return m, nil
+			}
+		}
+
 		// 正常模式
 		switch msg.String() {
 		case "q", "ctrl+c":
@@ -239,11 +284,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "a":
 			// 添加服务器
 			m.formMode = true
-			m.form = NewFormModel(nil, func(server config.Server) error {
-				if err := m.config.AddServer(server); err != nil {
-					return err
-				}
-				return config.Save(m.config)
+			m.form = NewFormModel(m.config, nil, func(server config.Server) error {
+				return m.updateConfig(func(cfg *config.Config) error {
+					return cfg.AddServer(server)
+				})
 			}, func() {
 				m.formMode = false
 			})
@@ -252,6 +296,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.form.height = m.height
 			return m, nil
 
+		case "i":
+			// 从 ~/.ssh/config 导入服务器
+			m.importMode = true
+			m.importer = NewImportModel(m.config, func(candidates []sshconfig.Candidate, selected map[string]bool) error {
+				return m.updateConfig(func(cfg *config.Config) error {
+					_, err := sshconfig.Apply(cfg, candidates, selected)
+					return err
+				})
+			})
+			m.importer.width = m.width
+			m.importer.height = m.height
+			return m, nil
+
 		case "d":
 			// 删除服务器
 			if len(m.config.Servers) == 0 {
@@ -278,15 +335,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			selectedItem := m.list.SelectedItem()
 			if item, ok := selectedItem.(item); ok {
 				m.formMode = true
-				serverCopy := item.server
-				m.form = NewFormModel(&serverCopy, func(server config.Server) error {
-					// 删除旧服务器
-					m.config.DeleteServer(item.server.Name)
-					// 添加新服务器
-					if err := m.config.AddServer(server); err != nil {
-						return err
-					}
-					return config.Save(m.config)
+				// 表单中只填写服务器自己设置的字段，继承的值保存时重新展开
+				serverCopy := m.config.ServerOverrides(item.server)
+				m.form = NewFormModel(m.config, &serverCopy, func(server config.Server) error {
+					return m.updateConfig(func(cfg *config.Config) error {
+						// 替换原来的服务器，修改写回服务器所在的文件
+						return cfg.ReplaceServer(item.server.Name, server)
+					})
 				}, func() {
 					m.formMode = false
 				})
@@ -316,6 +371,11 @@ func (m Model) View() string {
 		return m.form.View()
 	}
 
+	// 导入列表
+	if m.importMode {
+		return m.importer.View()
+	}
+
 	// 删除确认
 	if m.deleteConfirm {
 		selectedItem := m.list.SelectedItem()
@@ -353,7 +413,12 @@ func (m Model) View() string {
 	}
 	b.WriteString(title)
 	titleRemaining := m.width - len(title) - padding
-	if titleRemaining > 0 {
+	// 标题右侧显示自动同步状态
+	if status := m.syncStatusView(titleRemaining - 2); status != "" {
+		b.WriteString(strings.Repeat(" ", titleRemaining-lipgloss.Width(status)-1))
+		b.WriteString(status)
+		b.WriteString(" ")
+	} else if titleRemaining > 0 {
 		b.WriteString(strings.Repeat(" ", titleRemaining))
 	}
 	b.WriteString("\n")
@@ -403,7 +468,7 @@ func (m Model) View() string {
 		b.WriteString(strings.Repeat("─", separatorLen))
 	}
 	b.WriteString("\n")
-	help := " 操作: j/k 移动 h/l 翻页 gG跳转 | Enter 登录 | / 搜索 | a 添加 | d 删除 | e 编辑 | q 退出"
+	help := " 操作: j/k 移动 h/l 翻页 gG跳转 | Enter 登录 | / 搜索 | a 添加 | d 删除 | e 编辑 | i 导入 | q 退出"
 	b.WriteString(help)
 	b.WriteString("\n")
 	if separatorLen > 0 {
@@ -459,6 +524,8 @@ func NewModel() (*Model, error) {
 		config:   cfg,
 		search:   search,
 	}
+	// 开启自动同步时，启动后在后台拉取
+	m.initCmd = m.startAutoSync(sync.AutoPull)
 
 	return m, nil
 }
@@ -470,16 +537,28 @@ func Run() error {
 		return err
 	}
 
+	// 界面运行期间（例如自动同步后重新加载）发现的配置问题先暂存，退出界面后再输出，避免打乱界面
+	var issues []config.Issue
+	restore := config.SetIssueHandler(func(issue config.Issue) {
+		issues = append(issues, issue)
+	})
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	finalModel, err := p.Run()
+	restore()
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "警告: %s\n", issue)
+	}
 	if err != nil {
 		return fmt.Errorf("运行界面失败: %w", err)
 	}
 
 	// tea程序退出后，检查是否有待连接的服务器
 	if finalModel != nil {
-		if model, ok := finalModel.(Model); ok && model.pendingServer != nil {
-			connectToServer(*model.pendingServer)
+		if model, ok := finalModel.(Model); ok {
+			if model.pendingServer != nil {
+				connectToServer(model.config, *model.pendingServer)
+			}
+			model.waitAutoSync()
 		}
 	}
 
@@ -7,30 +7,35 @@ import (
 	"github.com/fijdemon/gssh/internal/ssh"
 )
 
-// connectToServer 连接到服务器
-func connectToServer(s config.Server) {
-	fmt.Printf("正在连接到 %s (%s)...\n", s.Name, s.GetAddress())
-
-	authConfig := ssh.AuthConfig{
-		Type:         s.Auth.Type,
-		Password:     s.Auth.Password,
-		IdentityFile: s.Auth.IdentityFile,
+// updateConfig 在配置锁内基于最新的配置执行修改并保存，成功后用保存的结果替换界面中的配置
+// 其他终端或后台同步在界面打开期间做的修改不会被覆盖
+func (m Model) updateConfig(fn func(cfg *config.Config) error) error {
+	cfg, err := config.Update(fn)
+	if err != nil {
+		return err
 	}
+	*m.config = *cfg
+	return nil
+}
 
-	if err := ssh.Connect(s.Hostname, s.User, s.Port, authConfig); err != nil {
+// connectToServer 连接到服务器（cfg 用于解析跳板机链）
+func connectToServer(cfg *config.Config, s config.Server) {
+	fmt.Printf("正在连接到 %s (%s)...\n", s.Name, s.GetAddress())
+
+	if _, err := ssh.ConnectServer(cfg, s); err != nil {
 		fmt.Printf("连接失败: %v\n", err)
 		return
 	}
 
-	// 更新最后使用时间
+	// 更新最后使用时间（在最新的配置上修改，不覆盖其他终端的修改）
 	s.UpdateLastUsed()
-	cfg, _ := config.Load()
-	for i := range cfg.Servers {
-		if cfg.Servers[i].Name == s.Name {
-			cfg.Servers[i].UpdateLastUsed()
-			config.Save(cfg)
-			break
+	config.Update(func(cfg *config.Config) error {
+		server, err := cfg.GetServer(s.Name)
+		if err != nil {
+			return config.ErrUnchanged
 		}
-	}
+		server.UpdateLastUsed()
+		return nil
+	})
 }
 
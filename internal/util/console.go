@@ -1,6 +1,53 @@
 package util
 
-// IsYes 判断输入是否为是
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// IsYes 判断输入是否为是（y 或 yes，不区分大小写）
 func IsYes(input string) bool {
-	return input == "y" || input == "Y"
+	input = strings.ToLower(strings.TrimSpace(input))
+	return input == "y" || input == "yes"
+}
+
+// ReadPassword 在终端读取不回显的输入
+// 提示输出到标准错误，不会混入 --json 等写到标准输出的结果
+func ReadPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr) // 换行
+	if err != nil {
+		return "", fmt.Errorf("读取密码失败: %w", err)
+	}
+	return string(secret), nil
+}
+
+// IsTerminal 判断标准输入是否为终端
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// DisplayWidth 计算字符串在终端中的显示宽度（中文等宽字符按 2 计算）
+func DisplayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// PadRight 在右侧补空格，使字符串达到指定的显示宽度
+func PadRight(s string, width int) string {
+	if pad := width - DisplayWidth(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
 }
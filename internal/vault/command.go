@@ -0,0 +1,85 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// commandTimeout 外部密码命令的超时时间（留出输入 GPG 口令等交互的时间）
+const commandTimeout = 2 * time.Minute
+
+// Resolve 获取密码：优先使用配置的值（保险库密文会被解密），否则执行 command 指定的外部命令
+// field 为命令对应的配置项名称，用于错误提示；两者都未配置时返回空字符串
+func Resolve(value, command, field string) (string, error) {
+	if value != "" || command == "" {
+		return Reveal(value)
+	}
+	return RunCommand(field, command)
+}
+
+// ResolveQuiet 与 Resolve 相同，但不在终端交互（用于后台任务和并行执行）：
+// 保险库未解锁时直接失败，外部命令不读取终端输入
+func ResolveQuiet(value, command, field string) (string, error) {
+	if value != "" || command == "" {
+		return RevealQuiet(value)
+	}
+	return RunCommandQuiet(field, command)
+}
+
+// RunCommand 执行外部命令并使用其标准输出的第一行作为密码
+// 密码只保存在内存中，不会写入配置文件
+func RunCommand(field, command string) (string, error) {
+	return runCommand(field, command, true)
+}
+
+// RunCommandQuiet 与 RunCommand 相同，但命令的标准输入为空，不会读取用户在终端的输入
+func RunCommandQuiet(field, command string) (string, error) {
+	return runCommand(field, command, false)
+}
+
+// runCommand 执行外部密码命令，interactive 为 false 时不把终端输入交给命令
+func runCommand(field, command string, interactive bool) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	// 交互时保留终端输入和错误输出，便于 pass/gopass 等工具提示输入口令
+	// 非交互时错误输出不写到终端（避免打乱 TUI 或其他输出），失败时附在错误信息中
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	if interactive {
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+	} else {
+		cmd.Stderr = &stderr
+	}
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("执行 %s 超时（%s）: %s", field, commandTimeout, command)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("执行 %s 失败: %s: %w: %s", field, command, err, msg)
+		}
+		return "", fmt.Errorf("执行 %s 失败: %s: %w", field, command, err)
+	}
+
+	secret, _, _ := strings.Cut(stdout.String(), "\n")
+	secret = strings.TrimRight(secret, "\r")
+	if secret == "" {
+		return "", fmt.Errorf("%s 没有输出密码: %s", field, command)
+	}
+	return secret, nil
+}
@@ -0,0 +1,262 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 同步内容的加密方式
+const (
+	PayloadPassphrase = "passphrase" // 团队共享口令
+	PayloadRecipients = "recipients" // 接收方的保险库公钥
+)
+
+// Payload 端到端加密的同步内容
+// 内容使用随机数据密钥以 XChaCha20-Poly1305 加密；数据密钥由共享口令经 scrypt 派生，
+// 或者由推送者的保险库私钥用 nacl/box 分别加密给每个接收方（接收方可以确认推送者身份）
+type Payload struct {
+	Version    int                `yaml:"version"`
+	Mode       string             `yaml:"mode"`
+	N          int                `yaml:"n,omitempty"`
+	R          int                `yaml:"r,omitempty"`
+	P          int                `yaml:"p,omitempty"`
+	Salt       string             `yaml:"salt,omitempty"`       // base64，passphrase 模式
+	Sender     string             `yaml:"sender,omitempty"`     // base64，推送者的保险库公钥
+	Recipients []PayloadRecipient `yaml:"recipients,omitempty"` // recipients 模式
+	Data       string             `yaml:"data"`                 // base64，nonce + 密文
+}
+
+// PayloadRecipient 加密给某个接收方的数据密钥
+type PayloadRecipient struct {
+	PublicKey string `yaml:"public_key"` // base64，接收方的保险库公钥
+	Key       string `yaml:"key"`        // base64，nonce + nacl/box 密文
+}
+
+// SealWithPassphrase 使用共享口令加密同步内容
+func SealWithPassphrase(plaintext []byte, passphrase string) (*Payload, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("同步口令不能为空")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	p := &Payload{
+		Version: 1,
+		Mode:    PayloadPassphrase,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, p.N, p.R, p.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+	if p.Data, err = p.sealData(key, plaintext); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SealForRecipients 使用本机保险库私钥将同步内容加密给 recipients（保险库公钥，base64）
+// 推送者自己总是接收方之一；保险库未解锁时会提示输入主密码
+func SealForRecipients(plaintext []byte, recipients []string) (*Payload, error) {
+	v, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("按公钥加密同步内容需要先运行 'gssh vault init'")
+	}
+	priv, err := unlockInteractive(v, true)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	p := &Payload{Version: 1, Mode: PayloadRecipients, Sender: v.PublicKey}
+	seen := map[string]bool{}
+	for _, r := range append([]string{v.PublicKey}, recipients...) {
+		pub, err := parsePublicKey(r)
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(pub[:])
+		if seen[encoded] {
+			continue
+		}
+		seen[encoded] = true
+
+		var nonce [24]byte
+		if _, err := rand.Read(nonce[:]); err != nil {
+			return nil, fmt.Errorf("生成随机数失败: %w", err)
+		}
+		sealed := box.Seal(nonce[:], key, &nonce, pub, priv)
+		p.Recipients = append(p.Recipients, PayloadRecipient{
+			PublicKey: encoded,
+			Key:       base64.StdEncoding.EncodeToString(sealed),
+		})
+	}
+
+	if p.Data, err = p.sealData(key, plaintext); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// OpenWithPassphrase 使用共享口令解密同步内容
+func (p *Payload) OpenWithPassphrase(passphrase string) ([]byte, error) {
+	if p.Mode != PayloadPassphrase {
+		return nil, fmt.Errorf("远程配置使用 %s 方式加密，与本地的 passphrase 设置不一致", p.Mode)
+	}
+	if p.Version != 1 {
+		return nil, fmt.Errorf("不支持的加密格式版本: %d", p.Version)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(p.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("加密内容格式错误: %w", err)
+	}
+	// 限制 scrypt 参数，避免被篡改的参数耗尽内存
+	if p.N <= 1 || p.N > 1<<20 || p.R <= 0 || p.R > 32 || p.P <= 0 || p.P > 16 {
+		return nil, fmt.Errorf("加密内容的密钥派生参数无效")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, p.N, p.R, p.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+
+	plain, err := p.openData(key)
+	if err != nil {
+		return nil, fmt.Errorf("解密失败：同步口令错误或内容已被篡改")
+	}
+	return plain, nil
+}
+
+// OpenAsRecipient 使用本机保险库私钥解密同步内容
+// 推送者必须是 trusted（保险库公钥，base64）之一或本机，否则视为被伪造
+func (p *Payload) OpenAsRecipient(trusted []string) ([]byte, error) {
+	if p.Mode != PayloadRecipients {
+		return nil, fmt.Errorf("远程配置使用 %s 方式加密，与本地的 recipients 设置不一致", p.Mode)
+	}
+	if p.Version != 1 {
+		return nil, fmt.Errorf("不支持的加密格式版本: %d", p.Version)
+	}
+
+	v, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("按公钥解密同步内容需要先运行 'gssh vault init'")
+	}
+
+	sender, err := parsePublicKey(p.Sender)
+	if err != nil {
+		return nil, fmt.Errorf("加密内容的推送者公钥无效")
+	}
+	if !containsKey(append([]string{v.PublicKey}, trusted...), sender) {
+		return nil, fmt.Errorf("远程配置的推送者 %s 不在 recipients 中，拒绝使用", p.Sender)
+	}
+
+	var sealed []byte
+	for _, r := range p.Recipients {
+		if r.PublicKey == v.PublicKey {
+			if sealed, err = base64.StdEncoding.DecodeString(r.Key); err != nil {
+				return nil, fmt.Errorf("加密内容格式错误: %w", err)
+			}
+			break
+		}
+	}
+	if sealed == nil {
+		return nil, fmt.Errorf("远程配置没有加密给本机（公钥 %s），请让团队成员将该公钥加入 recipients 后重新推送", v.PublicKey)
+	}
+	if len(sealed) < 24 {
+		return nil, fmt.Errorf("加密内容格式错误")
+	}
+
+	priv, err := unlockInteractive(v, true)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [24]byte
+	copy(nonce[:], sealed[:24])
+	key, ok := box.Open(nil, sealed[24:], &nonce, sender, priv)
+	if !ok {
+		return nil, fmt.Errorf("解密失败：内容已被篡改")
+	}
+
+	plain, err := p.openData(key)
+	if err != nil {
+		return nil, fmt.Errorf("解密失败：内容已被篡改")
+	}
+	return plain, nil
+}
+
+// sealData 使用数据密钥加密内容，加密方式作为附加数据参与校验
+func (p *Payload) sealData(key, plaintext []byte) (string, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", fmt.Errorf("初始化加密算法失败: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, p.additionalData())
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openData 使用数据密钥解密内容
+func (p *Payload) openData(key []byte) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(p.Data)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("密文过短")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], p.additionalData())
+}
+
+// additionalData 参与校验的头部信息（替换推送者公钥同样会导致解密失败）
+func (p *Payload) additionalData() []byte {
+	return []byte(fmt.Sprintf("gssh-sync:v%d:%s:%s", p.Version, p.Mode, p.Sender))
+}
+
+// parsePublicKey 解析 base64 编码的 X25519 公钥
+func parsePublicKey(value string) (*[32]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(raw) != 32 {
+		return nil, fmt.Errorf("无效的公钥: %s", value)
+	}
+	var pub [32]byte
+	copy(pub[:], raw)
+	return &pub, nil
+}
+
+// containsKey 判断公钥是否在列表中（忽略无法解析的项）
+func containsKey(keys []string, pub *[32]byte) bool {
+	for _, k := range keys {
+		if parsed, err := parsePublicKey(k); err == nil && *parsed == *pub {
+			return true
+		}
+	}
+	return false
+}
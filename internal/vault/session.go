@@ -0,0 +1,140 @@
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fijdemon/gssh/internal/util"
+)
+
+// session 解锁缓存，在有效期内无需再次输入主密码
+type session struct {
+	PrivateKey string    `json:"private_key"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// errNoRuntimeDir 没有内存文件系统可以存放解锁缓存
+var errNoRuntimeDir = errors.New("XDG_RUNTIME_DIR 未设置，无法安全地缓存解锁状态（解锁缓存只保存在内存文件系统中）；需要密码时会在终端提示输入主密码")
+
+// getSessionPath 获取解锁缓存路径
+// 缓存中是保险库私钥，只放在 XDG_RUNTIME_DIR（内存文件系统，仅当前用户可访问，注销或重启后自动清除），不写入磁盘
+func getSessionPath() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "", errNoRuntimeDir
+	}
+	return filepath.Join(dir, "gssh-vault.session"), nil
+}
+
+// CanCacheSession 检查当前环境能否缓存解锁状态，不能时返回原因（未设置 XDG_RUNTIME_DIR，例如 macOS、Windows）
+func CanCacheSession() error {
+	_, err := getSessionPath()
+	return err
+}
+
+// removeLegacySession 删除旧版本在没有 XDG_RUNTIME_DIR 时写入 ~/.gssh/vault.session 的解锁缓存
+func removeLegacySession() {
+	path, err := GetVaultPath()
+	if err != nil {
+		return
+	}
+	os.Remove(filepath.Join(filepath.Dir(path), "vault.session"))
+}
+
+// SaveSession 缓存已解锁的私钥，ttl 后过期
+func SaveSession(priv *[32]byte, ttl time.Duration) error {
+	removeLegacySession()
+	path, err := getSessionPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(session{
+		PrivateKey: base64.StdEncoding.EncodeToString(priv[:]),
+		ExpiresAt:  time.Now().Add(ttl),
+	})
+	if err != nil {
+		return fmt.Errorf("序列化解锁缓存失败: %w", err)
+	}
+
+	if err := util.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("写入解锁缓存失败: %w", err)
+	}
+	return nil
+}
+
+// Lock 清除解锁缓存
+func Lock() error {
+	unlockMu.Lock()
+	unlockedKey = nil
+	unlockMu.Unlock()
+
+	removeLegacySession()
+	path, err := getSessionPath()
+	if errors.Is(err, errNoRuntimeDir) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除解锁缓存失败: %w", err)
+	}
+	return nil
+}
+
+// SessionExpiresAt 返回解锁缓存的过期时间，未解锁时返回零值
+func SessionExpiresAt() time.Time {
+	path, err := getSessionPath()
+	if err != nil {
+		return time.Time{}
+	}
+	s, err := readSession(path)
+	if err != nil || time.Now().After(s.ExpiresAt) {
+		return time.Time{}
+	}
+	return s.ExpiresAt
+}
+
+// loadSession 读取未过期的解锁缓存，过期的缓存会被删除
+func loadSession() (*[32]byte, error) {
+	path, err := getSessionPath()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := readSession(path)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(s.ExpiresAt) {
+		os.Remove(path)
+		return nil, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(s.PrivateKey)
+	if err != nil || len(raw) != 32 {
+		return nil, fmt.Errorf("解锁缓存格式错误")
+	}
+	var priv [32]byte
+	copy(priv[:], raw)
+	return &priv, nil
+}
+
+// readSession 读取解锁缓存文件
+func readSession(path string) (*session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("解析解锁缓存失败: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,338 @@
+package vault
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fijdemon/gssh/internal/util"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// sealedPrefix 加密后的密码前缀
+const sealedPrefix = "vault:v1:"
+
+// scrypt 默认参数
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Vault 凭据保险库（~/.gssh/vault.yaml）
+// 密码使用公钥加密（无需解锁即可加密新密码），私钥由主密码经 scrypt 派生的密钥加密保存
+type Vault struct {
+	Version    int    `yaml:"version"`
+	KDF        string `yaml:"kdf"`
+	N          int    `yaml:"n"`
+	R          int    `yaml:"r"`
+	P          int    `yaml:"p"`
+	Salt       string `yaml:"salt"`        // base64
+	PublicKey  string `yaml:"public_key"`  // base64，X25519 公钥
+	PrivateKey string `yaml:"private_key"` // base64，XChaCha20-Poly1305 加密的私钥（nonce + 密文）
+}
+
+var (
+	// unlockedKey 当前进程内已解锁的私钥，避免同一进程内重复输入主密码
+	unlockedKey *[32]byte
+	unlockMu    sync.Mutex
+)
+
+// GetVaultPath 获取保险库文件路径
+func GetVaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户目录失败: %w", err)
+	}
+
+	configDir := filepath.Join(homeDir, ".gssh")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return "", fmt.Errorf("创建配置目录失败: %w", err)
+	}
+
+	return filepath.Join(configDir, "vault.yaml"), nil
+}
+
+// Load 加载保险库，未初始化时返回 nil
+func Load() (*Vault, error) {
+	path, err := GetVaultPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取保险库失败: %w", err)
+	}
+
+	var v Vault
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("解析保险库失败: %w", err)
+	}
+	if v.KDF != "scrypt" {
+		return nil, fmt.Errorf("不支持的密钥派生算法: %s", v.KDF)
+	}
+	return &v, nil
+}
+
+// Create 使用主密码创建新的保险库（生成新的密钥对，不写入文件）
+func Create(passphrase string) (*Vault, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("主密码不能为空")
+	}
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成密钥失败: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	v := &Vault{
+		Version:   1,
+		KDF:       "scrypt",
+		N:         scryptN,
+		R:         scryptR,
+		P:         scryptP,
+		Salt:      base64.StdEncoding.EncodeToString(salt),
+		PublicKey: base64.StdEncoding.EncodeToString(pub[:]),
+	}
+
+	key, err := v.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化加密算法失败: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, priv[:], nil)
+	v.PrivateKey = base64.StdEncoding.EncodeToString(sealed)
+
+	return v, nil
+}
+
+// Save 写入保险库文件
+func (v *Vault) Save() error {
+	path, err := GetVaultPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("序列化保险库失败: %w", err)
+	}
+
+	// 原子写入：rekey 等过程中断时保留原来的保险库，不会留下写了一半的私钥
+	if err := util.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("写入保险库失败: %w", err)
+	}
+	return nil
+}
+
+// Unlock 使用主密码解密私钥
+func (v *Vault) Unlock(passphrase string) (*[32]byte, error) {
+	key, err := v.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(v.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("保险库私钥格式错误: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化加密算法失败: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("保险库私钥格式错误")
+	}
+
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil || len(plain) != 32 {
+		return nil, fmt.Errorf("主密码错误")
+	}
+
+	var priv [32]byte
+	copy(priv[:], plain)
+	return &priv, nil
+}
+
+// Seal 使用保险库公钥加密密码
+func (v *Vault) Seal(plaintext string) (string, error) {
+	pub, err := v.publicKey()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := box.SealAnonymous(nil, []byte(plaintext), pub, rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("加密失败: %w", err)
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open 使用已解锁的私钥解密密码
+func (v *Vault) Open(value string, priv *[32]byte) (string, error) {
+	pub, err := v.publicKey()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("密文格式错误: %w", err)
+	}
+
+	plain, ok := box.OpenAnonymous(nil, sealed, pub, priv)
+	if !ok {
+		return "", fmt.Errorf("解密失败：密文已损坏或不属于当前保险库")
+	}
+	return string(plain), nil
+}
+
+// deriveKey 由主密码派生对称密钥
+func (v *Vault) deriveKey(passphrase string) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(v.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("保险库 salt 格式错误: %w", err)
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, v.N, v.R, v.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// publicKey 解析保险库公钥
+func (v *Vault) publicKey() (*[32]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(v.PublicKey)
+	if err != nil || len(raw) != 32 {
+		return nil, fmt.Errorf("保险库公钥格式错误")
+	}
+	var pub [32]byte
+	copy(pub[:], raw)
+	return &pub, nil
+}
+
+// matches 判断私钥是否与保险库公钥匹配（用于校验解锁缓存是否属于当前保险库）
+func (v *Vault) matches(priv *[32]byte) bool {
+	pub, err := v.publicKey()
+	if err != nil {
+		return false
+	}
+	derived, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	return err == nil && subtle.ConstantTimeCompare(derived, pub[:]) == 1
+}
+
+// IsSealed 判断值是否为保险库加密后的密文
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// Reveal 解密保险库密文；明文原样返回
+// 依次使用进程内缓存、unlock 缓存，都没有时在终端提示输入主密码
+func Reveal(value string) (string, error) {
+	return reveal(value, true)
+}
+
+// RevealQuiet 与 Reveal 相同，但保险库未解锁时直接失败，不提示输入主密码
+func RevealQuiet(value string) (string, error) {
+	return reveal(value, false)
+}
+
+// reveal 解密保险库密文，interactive 为 false 时不在终端提示输入主密码
+func reveal(value string, interactive bool) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+
+	v, err := Load()
+	if err != nil {
+		return "", err
+	}
+	if v == nil {
+		return "", fmt.Errorf("密码已加密，但保险库不存在（~/.gssh/vault.yaml）")
+	}
+
+	priv, err := unlockInteractive(v, interactive)
+	if err != nil {
+		return "", err
+	}
+	return v.Open(value, priv)
+}
+
+// Unlocked 判断是否无需输入主密码即可解密（进程内已解锁，或者存在有效的解锁缓存）
+// 保险库不存在时返回 true
+func Unlocked() bool {
+	v, err := Load()
+	if err != nil {
+		return false
+	}
+	if v == nil {
+		return true
+	}
+
+	unlockMu.Lock()
+	defer unlockMu.Unlock()
+
+	if unlockedKey != nil {
+		return true
+	}
+	if priv, err := loadSession(); err == nil && priv != nil && v.matches(priv) {
+		unlockedKey = priv
+		return true
+	}
+	return false
+}
+
+// unlockInteractive 获取已解锁的私钥，必要时提示输入主密码（interactive 为 false 时直接失败）
+func unlockInteractive(v *Vault, interactive bool) (*[32]byte, error) {
+	unlockMu.Lock()
+	defer unlockMu.Unlock()
+
+	if unlockedKey != nil {
+		return unlockedKey, nil
+	}
+
+	if priv, err := loadSession(); err == nil && priv != nil && v.matches(priv) {
+		unlockedKey = priv
+		return priv, nil
+	}
+
+	if !interactive || !util.IsTerminal() {
+		return nil, fmt.Errorf("保险库已锁定，请先运行 'gssh vault unlock'")
+	}
+
+	passphrase, err := util.ReadPassword("请输入保险库主密码: ")
+	if err != nil {
+		return nil, err
+	}
+	priv, err := v.Unlock(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	unlockedKey = priv
+	return priv, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/fijdemon/gssh/cmd"
 	"github.com/fijdemon/gssh/internal/config"
 	"github.com/fijdemon/gssh/internal/ssh"
+	"github.com/fijdemon/gssh/internal/sync"
 )
 
 // getVersion 获取版本号
@@ -43,21 +44,44 @@ func main() {
 	case "init":
 		err = cmd.RunInit()
 	case "pull":
-		err = cmd.RunPull()
+		err = cmd.RunPull(os.Args[2:])
 	case "push":
-		err = cmd.RunPush()
+		err = cmd.RunPush(os.Args[2:])
+	case "sync":
+		err = cmd.RunSync(os.Args[2:])
+	case "vault":
+		err = cmd.RunVault(os.Args[2:])
+	case "hostkey":
+		err = cmd.RunHostKey(os.Args[2:])
+	case "tunnel":
+		err = cmd.RunTunnel(os.Args[2:])
+	case "cp":
+		err = cmd.RunCopy(os.Args[2:])
+	case "exec":
+		err = cmd.RunExec(os.Args[2:])
+	case "import":
+		err = cmd.RunImport(os.Args[2:])
+	case "export":
+		err = cmd.RunExport(os.Args[2:])
+	case "config":
+		err = cmd.RunConfig(os.Args[2:])
+	case "show":
+		err = cmd.RunShow(os.Args[2:])
 	case "version":
 		fmt.Printf("gssh version %s\n", getVersion())
 	case "help", "-h", "--help":
 		printUsage()
 	default:
 		// 尝试作为服务器名称直接登录
-		if err := connectToServerByName(command); err != nil {
+		exitCode, err := connectToServerByName(command)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "错误: %v\n", err)
 			fmt.Fprintf(os.Stderr, "未知命令: %s\n", command)
 			printUsage()
 			os.Exit(1)
 		}
+		// 透传远程 shell 的退出码
+		os.Exit(exitCode)
 	}
 
 	if err != nil {
@@ -73,45 +97,79 @@ func printUsage() {
 	fmt.Println("  gssh                   打开交互式界面")
 	fmt.Println("  gssh init               初始化配置文件")
 	fmt.Println("  gssh <server-name>     直接登录指定服务器")
-	fmt.Println("  gssh pull               从云端拉取配置")
-	fmt.Println("  gssh push               推送配置到云端")
+	fmt.Println("  gssh show <server-name>  显示服务器展开 defaults 和模板之后的实际配置")
+	fmt.Println("  gssh pull [--profile name] [--prefer local|remote] [-y] [--dry-run] [--json]  从云端拉取配置并与本地修改合并")
+	fmt.Println("  gssh push [--profile name] [--prefer local|remote] [--dry-run] [--json]  合并远程修改后推送配置到云端")
+	fmt.Println("  gssh sync log|show|rollback [--profile name] [-y] [<id>]  查看或回滚同步后端保存的历史版本")
+	fmt.Println("  gssh exec [-g group] [-t tag...] [--parallel N] [--json] -- <command>  在多台服务器上并行执行命令")
+	fmt.Println("  gssh cp [-r] <src> <dst>  在本地与服务器之间复制文件（<server>:<path>）")
+	fmt.Println("  gssh import ssh-config [--dry-run] [-g group] [path]  从 ~/.ssh/config 导入服务器")
+	fmt.Println("  gssh export ssh-config [-g group] [-t tag...] [--write]  导出为 ssh_config（--write 自动维护 Include 文件）")
+	fmt.Println("  gssh config validate [--json] [path]  检查配置文件，按行号列出问题")
+	fmt.Println("  gssh tunnel <server> [forward...]  按配置建立端口转发（断线自动重连）")
+	fmt.Println("  gssh vault init|unlock|lock|rekey|pubkey  管理加密保存密码的保险库")
+	fmt.Println("                         （unlock 的解锁缓存需要 XDG_RUNTIME_DIR，macOS、Windows 等环境不支持，需要时在终端输入主密码）")
+	fmt.Println("  gssh hostkey list       列出已信任的主机密钥")
+	fmt.Println("  gssh hostkey forget <server>  删除服务器的主机密钥记录")
+	fmt.Println("  gssh hostkey trust <server>   确认并信任服务器当前的主机密钥")
+	fmt.Println("  gssh hostkey trust --sync [profile]  确认并信任 SSH 同步服务器的主机密钥")
 	fmt.Println("  gssh version            显示版本信息")
 	fmt.Println("  gssh help               显示帮助信息")
 	fmt.Println()
 }
 
-func connectToServerByName(name string) error {
+// connectToServerByName 按名称登录服务器，返回远程 shell 的退出码
+// 开启自动同步时在后台拉取配置，不等待同步完成即开始登录
+func connectToServerByName(name string) (int, error) {
 	cfg, err := config.Load()
 	if err != nil {
-		return fmt.Errorf("加载配置失败: %w", err)
+		return 0, fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	var autoSync chan error
+	if sync.AutoEnabled(cfg) {
+		autoSync = make(chan error, 1)
+		go func() { autoSync <- sync.AutoPull() }()
 	}
 
 	server, err := cfg.GetServer(name)
+	if err != nil && autoSync != nil {
+		// 本地没有该服务器时等待同步完成再查找（可能是刚在其他终端添加的）
+		syncErr := <-autoSync
+		autoSync = nil
+		if syncErr != nil {
+			return 0, fmt.Errorf("%w（自动同步失败: %v）", err, syncErr)
+		}
+		if cfg, err = config.Load(); err != nil {
+			return 0, fmt.Errorf("加载配置失败: %w", err)
+		}
+		server, err = cfg.GetServer(name)
+	}
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	fmt.Printf("正在连接到 %s (%s)...\n", server.Name, server.GetAddress())
 
-	authConfig := ssh.AuthConfig{
-		Type:         server.Auth.Type,
-		Password:     server.Auth.Password,
-		IdentityFile: server.Auth.IdentityFile,
-	}
+	exitCode, err := ssh.ConnectServer(cfg, *server)
 
-	if err := ssh.Connect(server.Hostname, server.User, server.Port, authConfig); err != nil {
-		return fmt.Errorf("连接失败: %w", err)
-	}
-
-	// 更新最后使用时间
-	server.UpdateLastUsed()
-	for i := range cfg.Servers {
-		if cfg.Servers[i].Name == server.Name {
-			cfg.Servers[i].UpdateLastUsed()
-			config.Save(cfg)
-			break
+	// 等待后台同步结束（避免退出时中断写入配置），再在最新的配置上更新最后使用时间
+	if autoSync != nil {
+		if syncErr := <-autoSync; syncErr != nil {
+			fmt.Fprintf(os.Stderr, "自动同步失败: %v\n", syncErr)
 		}
 	}
+	if err != nil {
+		return 0, fmt.Errorf("连接失败: %w", err)
+	}
+	config.Update(func(cfg *config.Config) error {
+		s, err := cfg.GetServer(server.Name)
+		if err != nil {
+			return config.ErrUnchanged
+		}
+		s.UpdateLastUsed()
+		return nil
+	})
 
-	return nil
+	return exitCode, nil
 }